@@ -1,7 +1,11 @@
 package api
 
 import (
+	"strings"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/app/apikey"
 	"github.com/nyzhehorodov/apicompanies/pkg/app/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/app/webhook"
 	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
 	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
 )
@@ -11,12 +15,51 @@ type API struct {
 	Server         *httpserver.Server
 	Logger         log.Interface
 	CompanyService company.Service
+	// APIKeyService is optional, API key management routes
+	// are registered only if it is set.
+	APIKeyService apikey.Service
+	// WebhookService is optional, webhook routes
+	// are registered only if it is set.
+	WebhookService webhook.Service
+	// CompanyCacheStats is optional, the status reports
+	// the hits and misses of the company cache if it is set.
+	CompanyCacheStats func() (hits, misses uint64)
 }
 
+// Init registers the routes. The server is expected to run
+// httpserver.RequestIDMiddleware before any other middleware.
 func (a *API) Init() {
 	a.Server.HandleGET("/v1/status", a.StatusHandler)
-	a.Server.HandlePOST("/v1/status", a.CompanyAddHandler)
-	a.Server.HandleGET("/v1/company", a.CompanyListHandler)
-	a.Server.HandlePUT("/v1/status", a.CompanyUpdateHandler)
-	a.Server.HandleDELETE("/v1/status", a.CompanyDeleteHandler)
+
+	a.Server.HandleGET("/v1/companies", a.CompanyListHandler)
+	a.Server.HandleGET("/v1/companies:export", a.CompanyExportHandler)
+	a.Server.HandleGET("/v1/companies/search", a.CompanySearchHandler)
+	a.Server.HandlePOST("/v1/companies", a.CompanyAddHandler)
+	a.Server.HandlePOST("/v1/companies:import", a.CompanyImportHandler)
+	a.Server.HandlePOST("/v1/companies:batch", a.CompanyBatchHandler)
+	a.Server.HandleGET("/v1/companies/:id", a.CompanyGetHandler)
+	a.Server.HandlePUT("/v1/companies/:id", a.CompanyUpdateHandler)
+	a.Server.HandlePATCH("/v1/companies/:id", a.CompanyPatchHandler)
+	a.Server.HandleDELETE("/v1/companies/:id", a.CompanyDeleteHandler)
+	a.Server.HandleGET("/v1/companies/:id/history", a.CompanyHistoryHandler)
+	a.Server.HandlePOST("/v1/companies/:id:restore", a.CompanyRestoreHandler)
+
+	if a.APIKeyService != nil {
+		a.Server.HandleGET("/v1/apikeys", a.APIKeyListHandler)
+		a.Server.HandlePOST("/v1/apikeys", a.APIKeyCreateHandler)
+		a.Server.HandleDELETE("/v1/apikeys/:id", a.APIKeyRevokeHandler)
+	}
+
+	if a.WebhookService != nil {
+		a.Server.HandleGET("/v1/webhooks", a.WebhookListHandler)
+		a.Server.HandlePOST("/v1/webhooks", a.WebhookCreateHandler)
+		a.Server.HandleGET("/v1/webhooks/:id", a.WebhookGetHandler)
+		a.Server.HandlePUT("/v1/webhooks/:id", a.WebhookUpdateHandler)
+		a.Server.HandleDELETE("/v1/webhooks/:id", a.WebhookDeleteHandler)
+		a.Server.HandleGET("/v1/webhooks/:id/deliveries", a.WebhookDeliveriesHandler)
+	}
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
 }
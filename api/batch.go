@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nyzhehorodov/apicompanies/api/v1"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func fromBatchRequest(req *v1.BatchRequest) []company.Operation {
+	ops := make([]company.Operation, 0, len(req.Operations))
+	for _, o := range req.Operations {
+		op := company.Operation{Op: o.Op, ID: o.ID, Version: o.Version}
+		if o.Company != nil {
+			op.Company = fromCompanyRequest(o.Company)
+		}
+		ops = append(ops, op)
+	}
+
+	return ops
+}
+
+// toBatchResponse renders the outcome of every operation,
+// failures are described with the problems their errors map to.
+func (a *API) toBatchResponse(r *http.Request, ops []company.Operation, report company.BatchReport) v1.BatchResponse {
+	resp := v1.BatchResponse{
+		Committed: report.Committed,
+		Results:   make([]v1.BatchResult, 0, len(report.Results)),
+	}
+
+	for i, res := range report.Results {
+		item := v1.BatchResult{Index: i, Op: ops[i].Op, Status: res.Status, ID: ops[i].ID}
+
+		if res.Status == company.BatchApplied && ops[i].Op != company.OpDelete {
+			c := toCompanyResponse(res.Company)
+			item.ID, item.Company = c.ID, &c
+		}
+		if res.Err != nil {
+			p, ok := errorProblem(r, res.Err)
+			if !ok {
+				a.Logger.Error(res.Err, "handler batch companies", "index", i, "requestId", p.RequestID)
+			}
+			// the problem is nested, the instance is the batch itself
+			p.Instance = ""
+			item.Error = &p
+		}
+
+		resp.Results = append(resp.Results, item)
+	}
+
+	return resp
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// etag returns a strong entity tag of the company version.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatch returns the company version required by the If-Match header.
+// Zero means the write is unconditional, that is the header is absent or "*".
+// Only a single strong entity tag is supported, weak tags never match.
+func ifMatch(r *http.Request) (int, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0, nil
+	}
+
+	unquoted, err := strconv.Unquote(h)
+	if err != nil {
+		return 0, &company.PreconditionFailedError{Detail: "If-Match must be a single strong entity tag"}
+	}
+
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, &company.PreconditionFailedError{Detail: "If-Match does not match any company version"}
+	}
+
+	return version, nil
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// Export formats.
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+)
+
+// exportFlushRows is the number of rows after which the response is flushed.
+const exportFlushRows = 1000
+
+// errNotAcceptable is returned if the Accept header allows no export format.
+var errNotAcceptable = fmt.Errorf("supported media types are %s and %s", csvContentType, ndjsonContentType)
+
+// exporter encodes companies into a response body.
+type exporter interface {
+	Encode(c company.Company) error
+	Flush() error
+}
+
+// exportFormat picks the format from the format query parameter,
+// falling back to the Accept header and then to NDJSON.
+func exportFormat(r *http.Request) (string, error) {
+	switch f := r.URL.Query().Get("format"); f {
+	case exportCSV, exportNDJSON:
+		return f, nil
+	case "":
+	default:
+		return "", &company.ValidationError{Detail: fmt.Sprintf("invalid format %q", f)}
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return exportNDJSON, nil
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, mr := range ranges {
+		switch mr.mediaType {
+		case csvContentType, "text/*":
+			return exportCSV, nil
+		case ndjsonContentType, "application/ndjson", "application/*", "*/*":
+			return exportNDJSON, nil
+		}
+	}
+
+	return "", errNotAcceptable
+}
+
+// exportDelimiter parses the CSV delimiter query parameter,
+// a single character or "tab", comma by default.
+func exportDelimiter(v string) (rune, error) {
+	if v == "" {
+		return ',', nil
+	}
+	if v == "tab" {
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(v)
+	if size != len(v) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, &company.ValidationError{Detail: fmt.Sprintf("invalid delimiter %q", v)}
+	}
+
+	return r, nil
+}
+
+// csvExporter writes a header row followed by a row per company.
+type csvExporter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVExporter(w io.Writer, delimiter rune) (*csvExporter, error) {
+	e := &csvExporter{w: csv.NewWriter(w)}
+	e.w.Comma = delimiter
+
+	header := append([]string{"id"}, csvColumns...)
+	if err := e.w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *csvExporter) Encode(c company.Company) error {
+	e.record = append(e.record[:0], strconv.Itoa(c.ID), c.Code, c.Name, c.Country, c.Website, c.Phone)
+
+	return e.w.Write(e.record)
+}
+
+func (e *csvExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonExporter writes a company object per line.
+type ndjsonExporter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONExporter(w io.Writer) *ndjsonExporter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonExporter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (e *ndjsonExporter) Encode(c company.Company) error {
+	return e.enc.Encode(toCompanyResponse(c))
+}
+
+func (e *ndjsonExporter) Flush() error {
+	return e.w.Flush()
+}
+
+// trackingWriter records whether the response has been started.
+type trackingWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *trackingWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *trackingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.started {
+		f.Flush()
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nyzhehorodov/apicompanies/api/v1"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/apikey"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/ctxparam"
+)
+
+func (a *API) APIKeyCreateHandler(w http.ResponseWriter, r *http.Request) {
+	req := &v1.APIKeyRequest{}
+	if err := decodeRequest(r, req); err != nil {
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "malformed request body")
+		return
+	}
+
+	key, plaintext, err := a.APIKeyService.Create(r.Context(), req.Name, req.Scopes, req.ExpiresAt)
+	if errors.Is(err, apikey.ErrInvalidName) || errors.Is(err, apikey.ErrInvalidScope) {
+		a.writeProblem(w, r, http.StatusUnprocessableEntity, v1.ProblemValidation, err.Error())
+		return
+	}
+	if err != nil {
+		a.writeError(w, r, err, "handler create api key")
+		return
+	}
+
+	w.Header().Set("Location", "/v1/apikeys/"+strconv.Itoa(key.ID))
+	resp := v1.CreatedAPIKey{APIKey: toAPIKeyResponse(key), Key: plaintext}
+	if err := encodeResponse(w, http.StatusCreated, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) APIKeyListHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := a.APIKeyService.List(r.Context())
+	if err != nil {
+		a.writeError(w, r, err, "handler list api keys")
+		return
+	}
+
+	resp := v1.APIKeysListResponse{Items: make([]v1.APIKey, 0, len(list))}
+	for _, k := range list {
+		resp.Items = append(resp.Items, toAPIKeyResponse(k))
+	}
+
+	if err := encodeResponse(w, http.StatusOK, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) APIKeyRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed api key id")
+		return
+	}
+
+	err = a.APIKeyService.Revoke(r.Context(), id)
+	if errors.Is(err, apikey.ErrNotFound) {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		a.writeError(w, r, err, "handler revoke api key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AuthenticateAPIKey implements httpserver.APIKeyAuthenticator.
+func (a *API) AuthenticateAPIKey(ctx context.Context, key string) (string, []string, error) {
+	k, err := a.APIKeyService.Authenticate(ctx, key)
+	if err != nil {
+		if !errors.Is(err, apikey.ErrInvalidKey) {
+			a.Logger.Error(err, "authenticate api key")
+		}
+		return "", nil, err
+	}
+
+	return "apikey:" + strconv.Itoa(k.ID), k.Scopes, nil
+}
+
+// APIKeyScope returns the scope an API key needs to serve the request.
+func APIKeyScope(r *http.Request) string {
+	switch path := r.URL.Path; {
+	case hasPathPrefix(path, "/v1/apikeys"):
+		return apikey.ScopeAPIKeysAdmin
+	case hasPathPrefix(path, "/v1/webhooks"):
+		return apikey.ScopeWebhooksAdmin
+	case strings.HasSuffix(path, ":restore") || r.URL.Query().Get(includeDeletedQuery) != "":
+		return apikey.ScopeCompaniesAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return apikey.ScopeCompaniesRead
+	default:
+		return apikey.ScopeCompaniesWrite
+	}
+}
+
+// AdminScope returns the admin scope a bearer token needs to serve
+// the request, reading and writing companies needs none.
+func AdminScope(r *http.Request) string {
+	switch scope := APIKeyScope(r); scope {
+	case apikey.ScopeCompaniesRead, apikey.ScopeCompaniesWrite:
+		return ""
+	default:
+		return scope
+	}
+}
+
+func toAPIKeyResponse(k apikey.APIKey) v1.APIKey {
+	return v1.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
@@ -0,0 +1,33 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/api"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/apikey"
+)
+
+func TestAdminScope(t *testing.T) {
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{method: http.MethodGet, target: "/v1/companies"},
+		{method: http.MethodPost, target: "/v1/companies"},
+		{method: http.MethodGet, target: "/v1/companies?includeDeleted=true", want: apikey.ScopeCompaniesAdmin},
+		{method: http.MethodPost, target: "/v1/companies/3:restore", want: apikey.ScopeCompaniesAdmin},
+		{method: http.MethodGet, target: "/v1/apikeys", want: apikey.ScopeAPIKeysAdmin},
+		{method: http.MethodPost, target: "/v1/webhooks", want: apikey.ScopeWebhooksAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			if got := api.AdminScope(httptest.NewRequest(tt.method, tt.target, nil)); got != tt.want {
+				t.Fatalf(`expected scope %q, got %q`, tt.want, got)
+			}
+		})
+	}
+}
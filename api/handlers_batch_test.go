@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func TestCompanyBatchHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	body := `{"operations":[
+		{"op":"create","company":{"name":"Acme","country":"US"}},
+		{"op":"delete","id":3,"version":2}
+	]}`
+
+	svc.EXPECT().Batch(gomock.Any(), gomock.Any(), true).DoAndReturn(func(_ context.Context, ops []company.Operation, _ bool) (company.BatchReport, error) {
+		if len(ops) != 2 || ops[0].Company.Name != "Acme" || ops[1].ID != 3 || ops[1].Version != 2 {
+			t.Fatalf(`unexpected operations %+v`, ops)
+		}
+		return company.BatchReport{Results: []company.OperationResult{
+			{Status: company.BatchAborted},
+			{Status: company.BatchFailed, Err: &company.PreconditionFailedError{Detail: "stale"}},
+		}}, nil
+	})
+
+	rec := serve(a, http.MethodPost, "/v1/companies:batch", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf(`expected status %d, got %d`, http.StatusUnprocessableEntity, rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"committed":false`) ||
+		!strings.Contains(body, `"index":1,"op":"delete","status":"failed","id":3`) ||
+		!strings.Contains(body, `"code":"precondition_failed"`) {
+		t.Fatalf(`unexpected body %q`, body)
+	}
+
+	svc.EXPECT().Batch(gomock.Any(), gomock.Any(), false).Return(company.BatchReport{
+		Results:   []company.OperationResult{{Status: company.BatchApplied, Company: company.Company{ID: 7, Name: "Acme"}}},
+		Committed: true,
+	}, nil)
+
+	rec = serve(a, http.MethodPost, "/v1/companies:batch?mode=best-effort", `{"operations":[{"op":"create","company":{"name":"Acme"}}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf(`expected status %d, got %d`, http.StatusOK, rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"status":"applied","id":7,"company":{"id":7`) {
+		t.Fatalf(`unexpected body %q`, body)
+	}
+}
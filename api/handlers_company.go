@@ -1,81 +1,621 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/nyzhehorodov/apicompanies/api/v1"
 	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
 	"github.com/nyzhehorodov/apicompanies/pkg/lib/ctxparam"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
 )
 
 func (a *API) CompanyAddHandler(w http.ResponseWriter, r *http.Request) {
 	req := &v1.CompanyRequest{}
 	if err := decodeRequest(r, req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "malformed request body")
 		return
 	}
 
-	comp := company.Company{
-		Code:    req.Code,
-		Name:    req.Name,
-		Country: req.Country,
-		Website: req.Website,
-		Phone:   req.Phone,
+	comp := fromCompanyRequest(req)
+
+	err := a.CompanyService.Add(actorContext(r), &comp)
+	if err != nil {
+		a.writeError(w, r, err, "handler add company")
+		return
+	}
+
+	w.Header().Set("Location", companyLocation(comp.ID))
+	w.Header().Set("ETag", etag(comp.Version))
+	if err := encodeResponse(w, http.StatusCreated, toCompanyResponse(comp)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) CompanyGetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed company id")
+		return
 	}
 
-	err := a.CompanyService.Add(comp)
+	includeDeleted, err := includeDeletedParam(r.URL.Query())
 	if err != nil {
-		a.Logger.Error(err, "handler add company")
-		w.WriteHeader(http.StatusInternalServerError)
+		a.writeError(w, r, err, "handler get company")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	comp, err := a.CompanyService.Get(actorContext(r), id, company.GetOptions{IncludeDeleted: includeDeleted})
+	if err != nil {
+		a.writeError(w, r, err, "handler get company")
+		return
+	}
+
+	w.Header().Set("ETag", etag(comp.Version))
+	if err := encodeResponse(w, http.StatusOK, toCompanyResponse(comp)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
 }
 
 func (a *API) CompanyListHandler(w http.ResponseWriter, r *http.Request) {
-	//	TODO
-	//	not implemented
+	opts, err := listOptions(r.URL.Query())
+	if err != nil {
+		a.writeError(w, r, err, "handler list companies")
+		return
+	}
+
+	page, total, err := a.CompanyService.List(actorContext(r), opts)
+	if err != nil {
+		a.writeError(w, r, err, "handler list companies")
+		return
+	}
+
+	resp := v1.CompaniesListResponse{
+		Items: make([]v1.Company, 0, len(page.Items)),
+		Total: total,
+		Links: v1.Links{
+			Next: pageLink(r.URL, page.Next),
+			Prev: pageLink(r.URL, page.Prev),
+		},
+	}
+	for _, c := range page.Items {
+		resp.Items = append(resp.Items, toCompanyResponse(c))
+	}
+
+	if err := encodeResponse(w, http.StatusOK, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+// CompanySearchHandler finds companies by the q query parameter,
+// optionally within a country.
+func (a *API) CompanySearchHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := searchOptions(r.URL.Query())
+	if err != nil {
+		a.writeError(w, r, err, "handler search companies")
+		return
+	}
+
+	page, err := a.CompanyService.Search(actorContext(r), opts)
+	if err != nil {
+		a.writeError(w, r, err, "handler search companies")
+		return
+	}
+
+	resp := v1.SearchResponse{
+		Items: make([]v1.SearchHit, 0, len(page.Items)),
+		Links: v1.Links{Next: pageLink(r.URL, page.Next)},
+	}
+	for _, hit := range page.Items {
+		resp.Items = append(resp.Items, v1.SearchHit{
+			Company:   toCompanyResponse(hit.Company),
+			Rank:      hit.Rank,
+			Highlight: hit.Highlight,
+		})
+	}
+
+	if err := encodeResponse(w, http.StatusOK, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+// CompanyExportHandler streams all companies matching the list filters
+// as CSV or NDJSON without paging.
+func (a *API) CompanyExportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	opts, err := listOptions(q)
+	if err != nil {
+		a.writeError(w, r, err, "handler export companies")
+		return
+	}
+
+	format, err := exportFormat(r)
+	if errors.Is(err, errNotAcceptable) {
+		a.writeProblem(w, r, http.StatusNotAcceptable, v1.ProblemNotAcceptable, err.Error())
+		return
+	}
+	if err != nil {
+		a.writeError(w, r, err, "handler export companies")
+		return
+	}
+
+	tw := &trackingWriter{ResponseWriter: w}
+
+	var exp exporter
+	switch format {
+	case exportCSV:
+		delimiter, err := exportDelimiter(q.Get("delimiter"))
+		if err != nil {
+			a.writeError(w, r, err, "handler export companies")
+			return
+		}
+		if exp, err = newCSVExporter(tw, delimiter); err != nil {
+			a.writeError(w, r, err, "handler export companies")
+			return
+		}
+		w.Header().Set("Content-Type", csvContentType+"; charset=utf-8; header=present")
+	default:
+		exp = newNDJSONExporter(tw)
+		w.Header().Set("Content-Type", ndjsonContentType)
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="companies.`+format+`"`)
+
+	var rows int
+	err = a.CompanyService.Export(actorContext(r), opts, func(c company.Company) error {
+		if err := exp.Encode(c); err != nil {
+			return err
+		}
+
+		if rows++; rows%exportFlushRows == 0 {
+			if err := exp.Flush(); err != nil {
+				return err
+			}
+			tw.Flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = exp.Flush()
+	}
+	if err == nil {
+		return
+	}
+
+	if !tw.started {
+		w.Header().Del("Content-Disposition")
+		a.writeError(w, r, err, "handler export companies")
+		return
+	}
+
+	// the status is sent already, abort the response to let the client know it is truncated
+	a.Logger.Error(err, "handler export companies", "requestId", httpserver.RequestID(r.Context()), "rows", rows)
+	panic(http.ErrAbortHandler)
 }
 
 func (a *API) CompanyUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed company id")
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		a.writeError(w, r, err, "handler update company")
+		return
+	}
+
 	req := &v1.CompanyRequest{}
 	if err := decodeRequest(r, req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "malformed request body")
 		return
 	}
 
-	comp := company.Company{
-		Code:    req.Code,
-		Name:    req.Name,
-		Country: req.Country,
-		Website: req.Website,
-		Phone:   req.Phone,
+	comp := fromCompanyRequest(req)
+	comp.ID = id
+	comp.Version = version
+
+	err = a.CompanyService.Update(actorContext(r), &comp)
+	if err != nil {
+		a.writeError(w, r, err, "handler update company")
+		return
+	}
+
+	w.Header().Set("ETag", etag(comp.Version))
+	if err := encodeResponse(w, http.StatusOK, toCompanyResponse(comp)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) CompanyPatchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed company id")
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		a.writeError(w, r, err, "handler patch company")
+		return
+	}
+
+	patch, err := decodePatch(r.Header.Get("Content-Type"), r.Body)
+	if errors.Is(err, errUnsupportedPatch) {
+		a.writeProblem(w, r, http.StatusUnsupportedMediaType, v1.ProblemUnsupportedMediaType, err.Error())
+		return
+	}
+	if err != nil {
+		a.writeError(w, r, err, "handler patch company")
+		return
 	}
 
-	err := a.CompanyService.Update(&comp)
+	comp, err := a.CompanyService.Patch(actorContext(r), id, version, patch)
 	if err != nil {
-		a.Logger.Error(err, "handler update company")
-		w.WriteHeader(http.StatusInternalServerError)
+		a.writeError(w, r, err, "handler patch company")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("ETag", etag(comp.Version))
+	if err := encodeResponse(w, http.StatusOK, toCompanyResponse(comp)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
 }
 
 func (a *API) CompanyDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := ctxparam.Int(r.Context(), "id")
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed company id")
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		a.writeError(w, r, err, "handler delete company")
+		return
+	}
+
+	err = a.CompanyService.Delete(actorContext(r), id, version)
+	if err != nil {
+		a.writeError(w, r, err, "handler delete company")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CompanyImportHandler creates companies from a CSV or NDJSON stream.
+// The mode query parameter selects an atomic (default) or a best-effort import.
+func (a *API) CompanyImportHandler(w http.ResponseWriter, r *http.Request) {
+	atomic, err := batchMode(r.URL.Query())
+	if err != nil {
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, err.Error())
+		return
+	}
+
+	source, err := newImportSource(r.Header.Get("Content-Type"), r.Body)
+	if errors.Is(err, errUnsupportedImport) {
+		a.writeProblem(w, r, http.StatusUnsupportedMediaType, v1.ProblemUnsupportedMediaType, err.Error())
+		return
+	}
+	if err != nil {
+		a.writeError(w, r, err, "handler import companies")
 		return
 	}
 
-	err = a.CompanyService.Delete(id)
+	report, err := a.CompanyService.Import(actorContext(r), source, atomic)
 	if err != nil {
-		a.Logger.Error(err, "handler delete company")
-		w.WriteHeader(http.StatusInternalServerError)
+		a.writeError(w, r, err, "handler import companies")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	status := http.StatusOK
+	if !report.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	if err := encodeResponse(w, status, toImportReportResponse(report)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+// CompanyBatchHandler applies a list of create, update and delete operations.
+// The mode query parameter selects an atomic (default) or a best-effort batch.
+func (a *API) CompanyBatchHandler(w http.ResponseWriter, r *http.Request) {
+	atomic, err := batchMode(r.URL.Query())
+	if err != nil {
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, err.Error())
+		return
+	}
+
+	req := &v1.BatchRequest{}
+	if err := decodeRequest(r, req); err != nil {
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "malformed request body")
+		return
+	}
+
+	ops := fromBatchRequest(req)
+
+	report, err := a.CompanyService.Batch(actorContext(r), ops, atomic)
+	if err != nil {
+		a.writeError(w, r, err, "handler batch companies")
+		return
+	}
+
+	status := http.StatusOK
+	if !report.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	if err := encodeResponse(w, status, a.toBatchResponse(r, ops, report)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) CompanyRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed company id")
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		a.writeError(w, r, err, "handler restore company")
+		return
+	}
+
+	comp, err := a.CompanyService.Restore(actorContext(r), id, version)
+	if err != nil {
+		a.writeError(w, r, err, "handler restore company")
+		return
+	}
+
+	w.Header().Set("ETag", etag(comp.Version))
+	if err := encodeResponse(w, http.StatusOK, toCompanyResponse(comp)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) CompanyHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed company id")
+		return
+	}
+
+	opts, err := historyOptions(r.URL.Query())
+	if err != nil {
+		a.writeError(w, r, err, "handler company history")
+		return
+	}
+
+	page, err := a.CompanyService.History(actorContext(r), id, opts)
+	if err != nil {
+		a.writeError(w, r, err, "handler company history")
+		return
+	}
+
+	resp := v1.CompanyHistoryResponse{Items: make([]v1.AuditRecord, 0, len(page.Items))}
+	for _, rec := range page.Items {
+		resp.Items = append(resp.Items, toAuditRecordResponse(rec))
+	}
+	if page.Next != 0 {
+		q := r.URL.Query()
+		q.Set("cursor", strconv.FormatInt(page.Next, 10))
+		resp.Links.Next = (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).String()
+	}
+
+	if err := encodeResponse(w, http.StatusOK, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func fromCompanyRequest(req *v1.CompanyRequest) company.Company {
+	return company.Company{
+		Code:    req.Code,
+		Name:    req.Name,
+		Country: req.Country,
+		Website: req.Website,
+		Phone:   req.Phone,
+	}
+}
+
+func toCompanyResponse(c company.Company) v1.Company {
+	return v1.Company{
+		ID:        c.ID,
+		Code:      c.Code,
+		Name:      c.Name,
+		Country:   c.Country,
+		Website:   c.Website,
+		Phone:     c.Phone,
+		DeletedAt: c.DeletedAt,
+		DeletedBy: c.DeletedBy,
+	}
+}
+
+func listOptions(q url.Values) (company.ListOptions, error) {
+	opts := company.ListOptions{
+		Name:    q.Get("name"),
+		Code:    q.Get("code"),
+		Country: q.Get("country"),
+		Website: q.Get("website"),
+		Phone:   q.Get("phone"),
+	}
+
+	includeDeleted, err := includeDeletedParam(q)
+	if err != nil {
+		return opts, err
+	}
+	opts.IncludeDeleted = includeDeleted
+
+	sorts, err := company.ParseSort(q.Get("sort"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Sort = sorts
+
+	opts.Limit, opts.Cursor, err = pageParams(q)
+
+	return opts, err
+}
+
+// pageParams parses the limit and cursor query parameters.
+func pageParams(q url.Values) (int, *company.Cursor, error) {
+	var (
+		limit  int
+		cursor *company.Cursor
+		err    error
+	)
+
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return 0, nil, &company.ValidationError{Detail: fmt.Sprintf("invalid limit %q", v)}
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		if cursor, err = company.DecodeCursor(v); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return limit, cursor, nil
+}
+
+func searchOptions(q url.Values) (company.SearchOptions, error) {
+	opts := company.SearchOptions{
+		Query:   q.Get("q"),
+		Country: q.Get("country"),
+	}
+
+	var err error
+	opts.Limit, opts.Cursor, err = pageParams(q)
+
+	return opts, err
+}
+
+// batchMode parses the mode query parameter of imports and batches,
+// it reports whether the changes must be applied atomically.
+func batchMode(q url.Values) (bool, error) {
+	switch mode := q.Get("mode"); mode {
+	case "", "atomic":
+		return true, nil
+	case "best-effort":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid mode %q", mode)
+	}
+}
+
+// includeDeletedQuery is the query parameter requesting soft-deleted companies.
+const includeDeletedQuery = "includeDeleted"
+
+// includeDeletedParam parses the includeDeleted query parameter.
+func includeDeletedParam(q url.Values) (bool, error) {
+	v := q.Get(includeDeletedQuery)
+	if v == "" {
+		return false, nil
+	}
+
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, &company.ValidationError{Detail: fmt.Sprintf("invalid %s %q", includeDeletedQuery, v)}
+	}
+
+	return include, nil
+}
+
+func historyOptions(q url.Values) (company.HistoryOptions, error) {
+	opts := company.HistoryOptions{Actor: q.Get("actor")}
+
+	parseTime := func(name string) (*time.Time, error) {
+		v := q.Get(name)
+		if v == "" {
+			return nil, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, &company.ValidationError{Detail: fmt.Sprintf("invalid %s %q, expected RFC 3339 time", name, v)}
+		}
+		return &t, nil
+	}
+
+	var err error
+	if opts.Since, err = parseTime("since"); err != nil {
+		return opts, err
+	}
+	if opts.Until, err = parseTime("until"); err != nil {
+		return opts, err
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return opts, &company.ValidationError{Detail: fmt.Sprintf("invalid limit %q", v)}
+		}
+		opts.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || cursor <= 0 {
+			return opts, &company.ValidationError{Detail: "malformed cursor", Err: company.ErrInvalidCursor}
+		}
+		opts.Cursor = cursor
+	}
+
+	return opts, nil
+}
+
+func toAuditRecordResponse(rec company.AuditRecord) v1.AuditRecord {
+	resp := v1.AuditRecord{
+		ID:        rec.ID,
+		Actor:     rec.Actor,
+		RequestID: rec.RequestID,
+		Operation: rec.Operation,
+		Changes:   make([]v1.FieldChange, 0, len(rec.Changes)),
+		CreatedAt: rec.CreatedAt,
+	}
+	for _, c := range rec.Changes {
+		resp.Changes = append(resp.Changes, v1.FieldChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+
+	return resp
+}
+
+// actorContext returns the request context carrying the authenticated
+// principal and the request id for the audit trail. Reads take it too,
+// so they see the writes of the same principal, see db.ReplicaSet.
+func actorContext(r *http.Request) context.Context {
+	ctx := r.Context()
+
+	return company.WithActor(ctx, company.Actor{
+		ID:        httpserver.Principal(ctx),
+		RequestID: httpserver.RequestID(ctx),
+	})
+}
+
+func companyLocation(id int) string {
+	return "/v1/companies/" + strconv.Itoa(id)
+}
+
+// pageLink returns the request URL pointing at the cursor position.
+func pageLink(u *url.URL, cursor *company.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+
+	q := u.Query()
+	q.Set("cursor", cursor.Encode())
+
+	link := url.URL{Path: u.Path, RawQuery: q.Encode()}
+
+	return link.String()
 }
@@ -0,0 +1,284 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/nyzhehorodov/apicompanies/api"
+	"github.com/nyzhehorodov/apicompanies/pkg/app/company/mocks"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+func newTestAPI(t *testing.T) (*api.API, *mocks.MockService) {
+	t.Helper()
+
+	svc := mocks.NewMockService(gomock.NewController(t))
+	a := &api.API{
+		Server:         httpserver.New(),
+		Logger:         log.Logger,
+		CompanyService: svc,
+	}
+	a.Server.AddMiddleware(httpserver.RequestIDMiddleware)
+	a.Init()
+
+	return a, svc
+}
+
+func serve(a *api.API, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	return rec
+}
+
+func TestCompanyAddHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c *company.Company) error {
+		if actor := company.ActorFrom(ctx); actor.RequestID == "" {
+			t.Fatalf(`expected request id in audit actor, got %+v`, actor)
+		}
+		c.ID = 7
+		return nil
+	})
+
+	rec := serve(a, http.MethodPost, "/v1/companies", `{"name":"Acme"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf(`expected status %d, got %d`, http.StatusCreated, rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/v1/companies/7" {
+		t.Fatalf(`expected location %q, got %q`, "/v1/companies/7", loc)
+	}
+	if !strings.Contains(rec.Body.String(), `"id":7`) {
+		t.Fatalf(`expected created company in body, got %q`, rec.Body.String())
+	}
+}
+
+func TestCompanyReadHandlersActor(t *testing.T) {
+	svc := mocks.NewMockService(gomock.NewController(t))
+	a := &api.API{
+		Server:         httpserver.New(),
+		Logger:         log.Logger,
+		CompanyService: svc,
+	}
+	a.Server.AddMiddleware(httpserver.RequestIDMiddleware)
+	a.Server.AddMiddleware(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r.WithContext(httpserver.WithPrincipal(r.Context(), "alice")))
+		}
+	})
+	a.Init()
+
+	// reads are routed by the actor to see its own writes
+	checkActor := func(ctx context.Context) {
+		if actor := company.ActorFrom(ctx); actor.ID != "alice" {
+			t.Fatalf(`expected the principal as the actor of a read, got %+v`, actor)
+		}
+	}
+	svc.EXPECT().Get(gomock.Any(), 3, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ int, _ company.GetOptions) (company.Company, error) {
+			checkActor(ctx)
+			return company.Company{ID: 3}, nil
+		})
+	svc.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ company.ListOptions) (company.Page, int, error) {
+			checkActor(ctx)
+			return company.Page{}, 0, nil
+		})
+	svc.EXPECT().Search(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ company.SearchOptions) (company.SearchPage, error) {
+			checkActor(ctx)
+			return company.SearchPage{}, nil
+		})
+	svc.EXPECT().History(gomock.Any(), 3, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ int, _ company.HistoryOptions) (company.HistoryPage, error) {
+			checkActor(ctx)
+			return company.HistoryPage{}, nil
+		})
+
+	for _, target := range []string{"/v1/companies/3", "/v1/companies", "/v1/companies/search?q=acme", "/v1/companies/3/history"} {
+		if rec := serve(a, http.MethodGet, target, ""); rec.Code != http.StatusOK {
+			t.Fatalf(`GET %s: expected status %d, got %d: %s`, target, http.StatusOK, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCompanyGetHandlerNotFound(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Get(gomock.Any(), 3, company.GetOptions{}).Return(company.Company{}, &company.NotFoundError{ID: 3})
+
+	rec := serve(a, http.MethodGet, "/v1/companies/3", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf(`expected status %d, got %d`, http.StatusNotFound, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf(`expected content type %q, got %q`, "application/problem+json", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `"code":"not_found"`) {
+		t.Fatalf(`expected problem code in body, got %q`, rec.Body.String())
+	}
+}
+
+func TestCompanyDeleteHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Delete(gomock.Any(), 3, 2).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/companies/3", nil)
+	req.Header.Set("If-Match", `"2"`)
+	rec := httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf(`expected status %d, got %d`, http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestCompanyPatchHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Patch(gomock.Any(), 3, 0, gomock.Any()).DoAndReturn(func(_ context.Context, id, _ int, p company.Patch) (company.Company, error) {
+		if p.Name == nil || *p.Name != "Acme" || p.Website == nil || *p.Website != "" {
+			t.Fatalf(`unexpected patch %+v`, p)
+		}
+		if p.Code != nil || p.Country != nil || p.Phone != nil {
+			t.Fatalf(`untouched fields must be nil, got %+v`, p)
+		}
+		return company.Company{ID: id, Name: "Acme", Version: 4}, nil
+	})
+
+	rec := serve(a, http.MethodPatch, "/v1/companies/3", `{"name":"Acme","website":null}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf(`expected status %d, got %d`, http.StatusOK, rec.Code)
+	}
+	if tag := rec.Header().Get("ETag"); tag != `"4"` {
+		t.Fatalf(`expected etag %q, got %q`, `"4"`, tag)
+	}
+}
+
+func TestCompanyUpdateHandlerPreconditionFailed(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, c *company.Company) error {
+		if c.Version != 2 {
+			t.Fatalf(`expected version 2, got %d`, c.Version)
+		}
+		return &company.PreconditionFailedError{Detail: "stale"}
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/companies/3", strings.NewReader(`{"name":"Acme"}`))
+	req.Header.Set("If-Match", `"2"`)
+	rec := httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf(`expected status %d, got %d`, http.StatusPreconditionFailed, rec.Code)
+	}
+}
+
+func TestCompanyRestoreHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Restore(gomock.Any(), 3, 5).Return(company.Company{ID: 3, Name: "Acme", Version: 6}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/companies/3:restore", nil)
+	req.Header.Set("If-Match", `"5"`)
+	rec := httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf(`expected status %d, got %d`, http.StatusOK, rec.Code)
+	}
+	if tag := rec.Header().Get("ETag"); tag != `"6"` {
+		t.Fatalf(`expected etag %q, got %q`, `"6"`, tag)
+	}
+
+	rec = serve(a, http.MethodPost, "/v1/companies/3:undo", "")
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf(`expected status %d for unknown verb, got %d`, http.StatusNotImplemented, rec.Code)
+	}
+}
+
+func TestCompanyHistoryHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	since := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.EXPECT().History(gomock.Any(), 3, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, opts company.HistoryOptions) (company.HistoryPage, error) {
+		if opts.Actor != "apikey:1" || opts.Since == nil || !opts.Since.Equal(since) || opts.Limit != 1 {
+			t.Fatalf(`unexpected options %+v`, opts)
+		}
+		phone := "+15550100"
+		return company.HistoryPage{
+			Items: []company.AuditRecord{{ID: 9, Actor: "apikey:1", Operation: company.OpPatch,
+				Changes: []company.FieldChange{{Field: "phone", After: &phone}}}},
+			Next: 9,
+		}, nil
+	})
+
+	rec := serve(a, http.MethodGet, "/v1/companies/3/history?actor=apikey:1&since=2022-01-01T00:00:00Z&limit=1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf(`expected status %d, got %d`, http.StatusOK, rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"field":"phone","before":null,"after":"+15550100"`) ||
+		!strings.Contains(body, `cursor=9`) {
+		t.Fatalf(`unexpected body %q`, body)
+	}
+
+	rec = serve(a, http.MethodGet, "/v1/companies/3/history?until=yesterday", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf(`expected status %d, got %d`, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestCompanySearchHandler(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().Search(gomock.Any(), company.SearchOptions{Query: "acme", Country: "US", Limit: 1}).Return(company.SearchPage{
+		Items: []company.SearchHit{{Company: company.Company{ID: 3, Name: "Acme"}, Rank: 0.5, Highlight: "<mark>Acme</mark>"}},
+		Next:  &company.Cursor{Sort: "rank", Keys: []string{"0.5"}, ID: 3},
+	}, nil)
+
+	rec := serve(a, http.MethodGet, "/v1/companies/search?q=acme&country=US&limit=1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf(`expected status %d, got %d`, http.StatusOK, rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"rank":0.5,"highlight":"\u003cmark\u003eAcme\u003c/mark\u003e"`) ||
+		!strings.Contains(body, `"next":"/v1/companies/search?country=US\u0026cursor=`) {
+		t.Fatalf(`unexpected body %q`, body)
+	}
+
+	// the company routes still take the segment for an id
+	svc.EXPECT().Get(gomock.Any(), 4, company.GetOptions{}).Return(company.Company{ID: 4}, nil)
+	if rec := serve(a, http.MethodGet, "/v1/companies/4", ""); rec.Code != http.StatusOK {
+		t.Fatalf(`expected status %d, got %d`, http.StatusOK, rec.Code)
+	}
+}
+
+func TestCompanyListHandlerTimeout(t *testing.T) {
+	a, svc := newTestAPI(t)
+
+	svc.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _ company.ListOptions) (company.Page, int, error) {
+		<-ctx.Done()
+		return company.Page{}, 0, fmt.Errorf("list companies: %w", ctx.Err())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/companies", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), time.Millisecond)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, req.WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf(`expected status %d, got %d`, http.StatusServiceUnavailable, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"code":"timeout"`) {
+		t.Fatalf(`expected timeout problem, got %q`, rec.Body.String())
+	}
+}
@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func TestCompanyExportHandler(t *testing.T) {
+	companies := []company.Company{
+		{ID: 1, Code: "A1", Name: "Acme; Inc", Country: "US"},
+		{ID: 2, Name: "Beta", Phone: "+15550100"},
+	}
+
+	tests := []struct {
+		name            string
+		target          string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "csv",
+			target:          "/v1/companies:export?format=csv&delimiter=%3B&country=US",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8; header=present",
+			wantBody:        "id;code;name;country;website;phone\n1;A1;\"Acme; Inc\";US;;\n2;;Beta;;;+15550100\n",
+		},
+		{
+			name:            "ndjson by accept",
+			target:          "/v1/companies:export?country=US",
+			accept:          "text/html, application/x-ndjson;q=0.9, text/csv;q=0.5",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBody: `{"id":1,"code":"A1","name":"Acme; Inc","country":"US","website":"","phone":""}` + "\n" +
+				`{"id":2,"code":"","name":"Beta","country":"","website":"","phone":"+15550100"}` + "\n",
+		},
+		{
+			name:       "not acceptable",
+			target:     "/v1/companies:export",
+			accept:     "application/xml",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:       "invalid delimiter",
+			target:     "/v1/companies:export?format=csv&delimiter=ab",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, svc := newTestAPI(t)
+
+			if tt.wantStatus == http.StatusOK {
+				svc.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, opts company.ListOptions, fn func(company.Company) error) error {
+					if opts.Country != "US" {
+						t.Fatalf(`unexpected options %+v`, opts)
+					}
+					for _, c := range companies {
+						if err := fn(c); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			a.Server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf(`expected status %d, got %d: %s`, tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.wantContentType {
+				t.Fatalf(`expected content type %q, got %q`, tt.wantContentType, ct)
+			}
+			if body := rec.Body.String(); body != tt.wantBody {
+				t.Fatalf(`expected body %q, got %q`, tt.wantBody, body)
+			}
+		})
+	}
+}
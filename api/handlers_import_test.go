@@ -0,0 +1,123 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func TestCompanyImportHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		query       string
+		body        string
+		atomic      bool
+		want        []company.ImportRow
+	}{
+		{
+			name:        "csv",
+			contentType: "text/csv",
+			body:        "name,country,code\nAcme,US,A1\n\"Beta, Inc\",GB,B2\nGamma\n",
+			atomic:      true,
+			want: []company.ImportRow{
+				{Line: 2, Company: company.Company{Name: "Acme", Country: "US", Code: "A1"}},
+				{Line: 3, Company: company.Company{Name: "Beta, Inc", Country: "GB", Code: "B2"}},
+				{Line: 4, Err: &company.ValidationError{}},
+			},
+		},
+		{
+			name:        "csv with a malformed row",
+			contentType: "text/csv",
+			query:       "?mode=best-effort",
+			body:        "name,country\nAc\"me,US\nBeta,GB\n",
+			want: []company.ImportRow{
+				{Line: 2, Err: &company.ValidationError{}},
+				{Line: 3, Company: company.Company{Name: "Beta", Country: "GB"}},
+			},
+		},
+		{
+			name:        "csv with blank lines and a multiline field",
+			contentType: "text/csv",
+			body:        "name,country\r\n\r\n\"Acme\r\nGroup\",US\r\n\nBeta,GB",
+			atomic:      true,
+			want: []company.ImportRow{
+				{Line: 3, Company: company.Company{Name: "Acme\nGroup", Country: "US"}},
+				{Line: 6, Company: company.Company{Name: "Beta", Country: "GB"}},
+			},
+		},
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			query:       "?mode=best-effort",
+			body:        "{\"name\":\"Acme\",\"country\":\"US\"}\n\n{\"name\":\n",
+			want: []company.ImportRow{
+				{Line: 1, Company: company.Company{Name: "Acme", Country: "US"}},
+				{Line: 3, Err: &company.ValidationError{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, svc := newTestAPI(t)
+
+			svc.EXPECT().Import(gomock.Any(), gomock.Any(), tt.atomic).DoAndReturn(
+				func(_ context.Context, src company.ImportSource, _ bool) (company.ImportReport, error) {
+					var got []company.ImportRow
+					for src.Next() {
+						got = append(got, src.Row())
+					}
+					if err := src.Err(); err != nil {
+						t.Fatalf(`unexpected error %v`, err)
+					}
+
+					if len(got) != len(tt.want) {
+						t.Fatalf(`expected %d rows, got %+v`, len(tt.want), got)
+					}
+					for i, row := range got {
+						want := tt.want[i]
+						if row.Line != want.Line || row.Company != want.Company || (row.Err == nil) != (want.Err == nil) {
+							t.Fatalf(`expected row %+v, got %+v`, want, row)
+						}
+					}
+
+					return company.ImportReport{Committed: true}, nil
+				})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/companies:import"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			a.Server.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf(`expected status %d, got %d: %s`, http.StatusOK, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompanyImportHandlerUnsupported(t *testing.T) {
+	a, _ := newTestAPI(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/companies:import", strings.NewReader("<xml/>"))
+	req.Header.Set("Content-Type", "application/xml")
+	rec := httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf(`expected status %d, got %d`, http.StatusUnsupportedMediaType, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/companies:import", strings.NewReader("name,founded\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	rec = httptest.NewRecorder()
+	a.Server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf(`expected status %d for unknown column, got %d`, http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/nyzhehorodov/apicompanies/api/v1"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/ctxparam"
+)
+
+func (a *API) WebhookCreateHandler(w http.ResponseWriter, r *http.Request) {
+	req := &v1.WebhookRequest{}
+	if err := decodeRequest(r, req); err != nil {
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "malformed request body")
+		return
+	}
+
+	hook := fromWebhookRequest(req)
+
+	if err := a.WebhookService.Create(r.Context(), &hook); err != nil {
+		a.writeWebhookError(w, r, err, "handler create webhook")
+		return
+	}
+
+	w.Header().Set("Location", "/v1/webhooks/"+strconv.Itoa(hook.ID))
+	resp := v1.CreatedWebhook{Webhook: toWebhookResponse(hook), Secret: hook.Secret}
+	if err := encodeResponse(w, http.StatusCreated, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) WebhookListHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := a.WebhookService.List(r.Context())
+	if err != nil {
+		a.writeWebhookError(w, r, err, "handler list webhooks")
+		return
+	}
+
+	resp := v1.WebhooksListResponse{Items: make([]v1.Webhook, 0, len(list))}
+	for _, hook := range list {
+		resp.Items = append(resp.Items, toWebhookResponse(hook))
+	}
+
+	if err := encodeResponse(w, http.StatusOK, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) WebhookGetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed webhook id")
+		return
+	}
+
+	hook, err := a.WebhookService.Get(r.Context(), id)
+	if err != nil {
+		a.writeWebhookError(w, r, err, "handler get webhook")
+		return
+	}
+
+	if err := encodeResponse(w, http.StatusOK, toWebhookResponse(hook)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) WebhookUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed webhook id")
+		return
+	}
+
+	req := &v1.WebhookRequest{}
+	if err := decodeRequest(r, req); err != nil {
+		a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "malformed request body")
+		return
+	}
+
+	hook := fromWebhookRequest(req)
+	hook.ID = id
+
+	if err := a.WebhookService.Update(r.Context(), &hook); err != nil {
+		a.writeWebhookError(w, r, err, "handler update webhook")
+		return
+	}
+
+	if err := encodeResponse(w, http.StatusOK, toWebhookResponse(hook)); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) WebhookDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed webhook id")
+		return
+	}
+
+	if err := a.WebhookService.Delete(r.Context(), id); err != nil {
+		a.writeWebhookError(w, r, err, "handler delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ctxparam.Int(r.Context(), "id")
+	if err != nil {
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, "malformed webhook id")
+		return
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			a.writeProblem(w, r, http.StatusBadRequest, v1.ProblemBadRequest, "invalid limit "+strconv.Quote(v))
+			return
+		}
+	}
+
+	list, err := a.WebhookService.Deliveries(r.Context(), id, limit)
+	if err != nil {
+		a.writeWebhookError(w, r, err, "handler list webhook deliveries")
+		return
+	}
+
+	resp := v1.WebhookDeliveriesResponse{Items: make([]v1.WebhookDelivery, 0, len(list))}
+	for _, d := range list {
+		resp.Items = append(resp.Items, v1.WebhookDelivery{
+			ID:         d.ID,
+			EventID:    d.EventID,
+			EventType:  d.EventType,
+			Attempt:    d.Attempt,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			DurationMs: d.Duration.Milliseconds(),
+			CreatedAt:  d.CreatedAt,
+		})
+	}
+
+	if err := encodeResponse(w, http.StatusOK, resp); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+func (a *API) writeWebhookError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		a.writeProblem(w, r, http.StatusNotFound, v1.ProblemNotFound, webhook.ErrNotFound.Error())
+	case errors.Is(err, webhook.ErrInvalidURL),
+		errors.Is(err, webhook.ErrInvalidEvent),
+		errors.Is(err, webhook.ErrInvalidSecret):
+		a.writeProblem(w, r, http.StatusUnprocessableEntity, v1.ProblemValidation, err.Error())
+	default:
+		a.writeError(w, r, err, msg)
+	}
+}
+
+func fromWebhookRequest(req *v1.WebhookRequest) webhook.Webhook {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	return webhook.Webhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: req.Secret,
+		Active: active,
+	}
+}
+
+func toWebhookResponse(w webhook.Webhook) v1.Webhook {
+	return v1.Webhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		Active:    w.Active,
+		Failures:  w.Failures,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
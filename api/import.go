@@ -0,0 +1,254 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/nyzhehorodov/apicompanies/api/v1"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// Import media types.
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// maxNDJSONLine limits the length of a single NDJSON row.
+const maxNDJSONLine = 1 << 20
+
+// errUnsupportedImport is returned for a request body of unknown media type.
+var errUnsupportedImport = fmt.Errorf("supported media types are %s and %s", csvContentType, ndjsonContentType)
+
+// newImportSource returns a streaming source of the rows of a CSV or NDJSON body.
+func newImportSource(contentType string, body io.Reader) (company.ImportSource, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errUnsupportedImport
+	}
+
+	switch mediaType {
+	case csvContentType:
+		return newCSVSource(body, params["header"] != "absent")
+	case ndjsonContentType, "application/ndjson", "application/jsonl":
+		return newNDJSONSource(body), nil
+	default:
+		return nil, errUnsupportedImport
+	}
+}
+
+// csvColumns are the columns of a CSV import in the default order.
+var csvColumns = []string{"code", "name", "country", "website", "phone"}
+
+// csvSource reads companies from CSV. The header row names the columns,
+// columns may come in any order and missing ones are left empty.
+type csvSource struct {
+	r       *csv.Reader
+	lines   *lineReader
+	columns []string
+	row     company.ImportRow
+	err     error
+}
+
+func newCSVSource(body io.Reader, header bool) (*csvSource, error) {
+	lines := &lineReader{r: bufio.NewReader(body), lineStart: true}
+	r := csv.NewReader(lines)
+	r.ReuseRecord = true
+	r.FieldsPerRecord = -1
+
+	s := &csvSource{r: r, lines: lines, columns: csvColumns}
+	if !header {
+		return s, nil
+	}
+
+	record, err := r.Read()
+	if err != nil {
+		return nil, &company.ValidationError{Detail: fmt.Sprintf("read csv header: %v", err)}
+	}
+
+	s.columns = make([]string, 0, len(record))
+	for _, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := companyField(&company.Company{}, name); !ok {
+			return nil, &company.ValidationError{Detail: fmt.Sprintf("unknown csv column %q", name)}
+		}
+		s.columns = append(s.columns, name)
+	}
+
+	return s, nil
+}
+
+func (s *csvSource) Next() bool {
+	record, err := s.r.Read()
+	if errors.Is(err, io.EOF) {
+		return false
+	}
+
+	// the position of the record is known only if it was read
+	var perr *csv.ParseError
+	switch {
+	case errors.As(err, &perr):
+		s.row = company.ImportRow{
+			Line: perr.StartLine,
+			Err:  &company.ValidationError{Detail: perr.Err.Error()},
+		}
+		return true
+	case err != nil:
+		s.err = err
+		return false
+	}
+
+	// the record ends on the last line read, quoted fields may span lines
+	s.row = company.ImportRow{Line: s.lines.lines}
+	for _, field := range record {
+		s.row.Line -= strings.Count(field, "\n")
+	}
+
+	switch {
+	case len(record) != len(s.columns):
+		s.row.Err = &company.ValidationError{
+			Detail: fmt.Sprintf("expected %d fields, got %d", len(s.columns), len(record)),
+		}
+		return true
+	}
+
+	for i, name := range s.columns {
+		field, _ := companyField(&s.row.Company, name)
+		*field = record[i]
+	}
+
+	return true
+}
+
+func (s *csvSource) Row() company.ImportRow {
+	return s.row
+}
+
+func (s *csvSource) Err() error {
+	return s.err
+}
+
+// lineReader hands its input over a line at a time at most, so that
+// the lines read through it are the lines of the records returned
+// by the csv reader, which does not read ahead.
+type lineReader struct {
+	r         *bufio.Reader
+	lines     int
+	lineStart bool
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	for n := 0; n < len(p); n++ {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if l.lineStart {
+			l.lines++
+		}
+		p[n], l.lineStart = b, b == '\n'
+		if l.lineStart {
+			return n + 1, nil
+		}
+	}
+
+	return len(p), nil
+}
+
+// ndjsonSource reads a company request object per line, blank lines are skipped.
+type ndjsonSource struct {
+	scanner *bufio.Scanner
+	line    int
+	row     company.ImportRow
+}
+
+func newNDJSONSource(body io.Reader) *ndjsonSource {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	return &ndjsonSource{scanner: scanner}
+}
+
+func (s *ndjsonSource) Next() bool {
+	for s.scanner.Scan() {
+		s.line++
+
+		data := s.scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		s.row = company.ImportRow{Line: s.line}
+
+		var req v1.CompanyRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.row.Err = &company.ValidationError{Detail: "malformed json: " + err.Error()}
+			return true
+		}
+		s.row.Company = fromCompanyRequest(&req)
+
+		return true
+	}
+
+	return false
+}
+
+func (s *ndjsonSource) Row() company.ImportRow {
+	return s.row
+}
+
+func (s *ndjsonSource) Err() error {
+	return s.scanner.Err()
+}
+
+// companyField returns the company field addressed by a column name.
+func companyField(c *company.Company, name string) (*string, bool) {
+	switch name {
+	case "code":
+		return &c.Code, true
+	case "name":
+		return &c.Name, true
+	case "country":
+		return &c.Country, true
+	case "website":
+		return &c.Website, true
+	case "phone":
+		return &c.Phone, true
+	default:
+		return nil, false
+	}
+}
+
+func toImportReportResponse(report company.ImportReport) v1.ImportReport {
+	resp := v1.ImportReport{
+		Committed:  report.Committed,
+		Accepted:   report.Accepted,
+		Rejected:   report.Rejected,
+		Duplicates: report.Duplicates,
+		Rows:       make([]v1.ImportRow, 0, len(report.Rows)),
+	}
+
+	for _, res := range report.Rows {
+		row := v1.ImportRow{Line: res.Line, Status: res.Status, ID: res.ID}
+
+		var verr *company.ValidationError
+		if errors.As(res.Err, &verr) {
+			row.Detail = verr.Detail
+			for _, f := range verr.Fields {
+				row.Errors = append(row.Errors, v1.FieldError{Field: f.Field, Rule: f.Rule, Message: f.Message})
+			}
+		} else if res.Err != nil {
+			row.Detail = res.Err.Error()
+		}
+
+		resp.Rows = append(resp.Rows, row)
+	}
+
+	return resp
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// Patch document media types.
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// errUnsupportedPatch is returned for a request body of unknown media type.
+var errUnsupportedPatch = fmt.Errorf("supported media types are %s and %s", mergePatchContentType, jsonPatchContentType)
+
+// decodePatch reads an RFC 7396 JSON Merge Patch or an RFC 6902 JSON Patch
+// document, depending on the content type, into a company patch.
+func decodePatch(contentType string, body io.Reader) (company.Patch, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		return company.Patch{}, errUnsupportedPatch
+	}
+
+	switch mediaType {
+	case mergePatchContentType, "application/json", "":
+		return decodeMergePatch(body)
+	case jsonPatchContentType:
+		return decodeJSONPatch(body)
+	default:
+		return company.Patch{}, errUnsupportedPatch
+	}
+}
+
+// patchField returns the patch field addressed by a JSON member name.
+func patchField(p *company.Patch, name string) (**string, bool) {
+	switch name {
+	case "code":
+		return &p.Code, true
+	case "name":
+		return &p.Name, true
+	case "country":
+		return &p.Country, true
+	case "website":
+		return &p.Website, true
+	case "phone":
+		return &p.Phone, true
+	default:
+		return nil, false
+	}
+}
+
+// decodeMergePatch decodes a merge patch, null clears the member.
+func decodeMergePatch(body io.Reader) (company.Patch, error) {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return company.Patch{}, &company.ValidationError{Detail: "merge patch must be a JSON object", Err: err}
+	}
+
+	var (
+		p    company.Patch
+		errs []company.FieldError
+	)
+	for name, raw := range doc {
+		field, ok := patchField(&p, name)
+		if !ok {
+			errs = append(errs, company.FieldError{Field: name, Rule: "unknown", Message: "is not a company field"})
+			continue
+		}
+
+		val := ""
+		if !bytes.Equal(raw, []byte("null")) {
+			if err := json.Unmarshal(raw, &val); err != nil {
+				errs = append(errs, company.FieldError{Field: name, Rule: "type", Message: "must be a string or null"})
+				continue
+			}
+		}
+		*field = &val
+	}
+
+	if len(errs) > 0 {
+		return company.Patch{}, &company.ValidationError{Detail: "merge patch is invalid", Fields: errs}
+	}
+
+	return p, nil
+}
+
+type jsonPatchOp struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// decodeJSONPatch decodes a JSON Patch. Only add, replace and remove
+// operations on top-level company fields are supported, remove clears the field.
+func decodeJSONPatch(body io.Reader) (company.Patch, error) {
+	var ops []jsonPatchOp
+	if err := json.NewDecoder(body).Decode(&ops); err != nil {
+		return company.Patch{}, &company.ValidationError{Detail: "JSON patch must be an array of operations", Err: err}
+	}
+
+	var p company.Patch
+	for i, op := range ops {
+		name := strings.TrimPrefix(op.Path, "/")
+		field, ok := patchField(&p, name)
+		if !ok || !strings.HasPrefix(op.Path, "/") {
+			return company.Patch{}, &company.ValidationError{Detail: fmt.Sprintf("operation %d: unsupported path %q", i, op.Path)}
+		}
+
+		val := ""
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return company.Patch{}, &company.ValidationError{Detail: fmt.Sprintf("operation %d: value is required", i)}
+			}
+			if err := json.Unmarshal(*op.Value, &val); err != nil {
+				return company.Patch{}, &company.ValidationError{Detail: fmt.Sprintf("operation %d: value must be a string", i)}
+			}
+		case "remove":
+		default:
+			return company.Patch{}, &company.ValidationError{Detail: fmt.Sprintf("operation %d: unsupported op %q", i, op.Op)}
+		}
+		*field = &val
+	}
+
+	return p, nil
+}
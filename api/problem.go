@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/nyzhehorodov/apicompanies/api/v1"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+const problemContentType = "application/problem+json"
+
+// writeProblem renders an RFC 7807 problem response.
+func (a *API) writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	a.renderProblem(w, newProblem(r, status, code, detail))
+}
+
+func newProblem(r *http.Request, status int, code, detail string) v1.Problem {
+	return v1.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: httpserver.RequestID(r.Context()),
+	}
+}
+
+func (a *API) renderProblem(w http.ResponseWriter, p v1.Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		a.Logger.Error(err, "got an error processing response")
+	}
+}
+
+// writeError renders a problem response for an error returned by the services.
+// Unexpected errors are logged and reported without details.
+func (a *API) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	p, ok := errorProblem(r, err)
+	if !ok {
+		a.Logger.Error(err, msg, "requestId", httpserver.RequestID(r.Context()))
+	}
+
+	a.renderProblem(w, p)
+}
+
+// errorProblem maps an error returned by the services to a problem,
+// it returns false for unexpected errors, which are reported without details.
+func errorProblem(r *http.Request, err error) (v1.Problem, bool) {
+	var (
+		notFound     *company.NotFoundError
+		conflict     *company.ConflictError
+		validation   *company.ValidationError
+		precondition *company.PreconditionFailedError
+	)
+
+	switch {
+	case errors.As(err, &notFound):
+		return newProblem(r, http.StatusNotFound, v1.ProblemNotFound, notFound.Error()), true
+	case errors.As(err, &conflict):
+		return newProblem(r, http.StatusConflict, v1.ProblemConflict, conflict.Detail), true
+	case errors.As(err, &validation) && len(validation.Fields) > 0:
+		p := newProblem(r, http.StatusUnprocessableEntity, v1.ProblemValidation, validation.Detail)
+		p.Errors = make([]v1.FieldError, 0, len(validation.Fields))
+		for _, f := range validation.Fields {
+			p.Errors = append(p.Errors, v1.FieldError{Field: f.Field, Rule: f.Rule, Message: f.Message})
+		}
+		return p, true
+	case errors.As(err, &validation):
+		return newProblem(r, http.StatusBadRequest, v1.ProblemValidation, validation.Detail), true
+	case errors.As(err, &precondition):
+		return newProblem(r, http.StatusPreconditionFailed, v1.ProblemPreconditionFailed, precondition.Detail), true
+	case errors.Is(err, context.DeadlineExceeded):
+		return newProblem(r, http.StatusServiceUnavailable, v1.ProblemTimeout, "request timed out"), true
+	case errors.Is(err, context.Canceled):
+		// the client has gone and will not see the problem
+		return newProblem(r, http.StatusServiceUnavailable, v1.ProblemTimeout, "request canceled"), true
+	default:
+		return newProblem(r, http.StatusInternalServerError, v1.ProblemInternal, ""), false
+	}
+}
@@ -14,18 +14,30 @@ type StatusResponse struct {
 	Version   string `json:"version"`
 	GitCommit string `json:"gitCommit"`
 	BuildDate string `json:"buildDate"`
+	// Cache is set when the company cache is enabled.
+	Cache *CacheStatus `json:"cache,omitempty"`
+}
+
+// CacheStatus counts the lookups of the company cache since the start.
+type CacheStatus struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
 }
 
 func (a *API) StatusHandler(w http.ResponseWriter, _ *http.Request) {
-	jsResp, err := json.Marshal(
-		StatusResponse{
-			Health:    "ok",
-			App:       version.AppName(),
-			Version:   version.AppVersion(),
-			GitCommit: version.Commit(),
-			BuildDate: version.Date(),
-		},
-	)
+	status := StatusResponse{
+		Health:    "ok",
+		App:       version.AppName(),
+		Version:   version.AppVersion(),
+		GitCommit: version.Commit(),
+		BuildDate: version.Date(),
+	}
+	if a.CompanyCacheStats != nil {
+		hits, misses := a.CompanyCacheStats()
+		status.Cache = &CacheStatus{Hits: hits, Misses: misses}
+	}
+
+	jsResp, err := json.Marshal(status)
 	if err != nil {
 		a.Logger.Error(err, "serialize status response")
 	}
@@ -9,8 +9,9 @@ func decodeRequest(r *http.Request, req interface{}) error {
 	return json.NewDecoder(r.Body).Decode(req)
 }
 
-func encodeResponse(w http.ResponseWriter, resp interface{}) error {
+func encodeResponse(w http.ResponseWriter, status int, resp interface{}) error {
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
 
 	return json.NewEncoder(w).Encode(resp)
 }
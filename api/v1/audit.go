@@ -0,0 +1,23 @@
+package v1
+
+import "time"
+
+type FieldChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+type AuditRecord struct {
+	ID        int64         `json:"id"`
+	Actor     string        `json:"actor"`
+	RequestID string        `json:"requestId,omitempty"`
+	Operation string        `json:"operation"`
+	Changes   []FieldChange `json:"changes"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+type CompanyHistoryResponse struct {
+	Items []AuditRecord `json:"items"`
+	Links Links         `json:"links"`
+}
@@ -0,0 +1,35 @@
+package v1
+
+// BatchRequest lists the operations of a batch in the order they are applied.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is one of create, update and delete.
+// ID selects the company of update and delete, Version is
+// the expected version, Company holds the values of create and update.
+type BatchOperation struct {
+	Op      string          `json:"op"`
+	ID      int             `json:"id,omitempty"`
+	Version int             `json:"version,omitempty"`
+	Company *CompanyRequest `json:"company,omitempty"`
+}
+
+// BatchResponse describes the outcome of a batch.
+// Committed is false if an atomic batch was rolled back.
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchResult is the outcome of a single operation, Status is one of
+// applied, failed and aborted. Company is set for applied creates
+// and updates, Error for the failed operations.
+type BatchResult struct {
+	Index   int      `json:"index"`
+	Op      string   `json:"op"`
+	Status  string   `json:"status"`
+	ID      int      `json:"id,omitempty"`
+	Company *Company `json:"company,omitempty"`
+	Error   *Problem `json:"error,omitempty"`
+}
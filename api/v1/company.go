@@ -1,5 +1,7 @@
 package v1
 
+import "time"
+
 type CompanyRequest struct {
 	Code    string `json:"code"`
 	Name    string `json:"name"`
@@ -11,13 +13,22 @@ type CompanyRequest struct {
 type CompaniesListResponse struct {
 	Items []Company `json:"items"`
 	Total int       `json:"total"`
+	Links Links     `json:"links"`
+}
+
+// Links holds URLs of the adjacent pages, empty when there is no such page.
+type Links struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
 
 type Company struct {
-	ID      int    `json:"id"`
-	Code    int    `json:"code"`
-	Name    string `json:"name"`
-	Country string `json:"country"`
-	Website string `json:"website"`
-	Phone   string `json:"phone"`
+	ID        int        `json:"id"`
+	Code      string     `json:"code"`
+	Name      string     `json:"name"`
+	Country   string     `json:"country"`
+	Website   string     `json:"website"`
+	Phone     string     `json:"phone"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy string     `json:"deletedBy,omitempty"`
 }
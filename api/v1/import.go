@@ -0,0 +1,21 @@
+package v1
+
+// ImportReport describes the outcome of a bulk import.
+// Committed is false if an atomic import was rolled back.
+type ImportReport struct {
+	Committed  bool        `json:"committed"`
+	Accepted   int         `json:"accepted"`
+	Rejected   int         `json:"rejected"`
+	Duplicates int         `json:"duplicates"`
+	Rows       []ImportRow `json:"rows"`
+}
+
+// ImportRow is the outcome of a single input row,
+// Status is one of accepted, rejected and duplicate.
+type ImportRow struct {
+	Line   int          `json:"line"`
+	Status string       `json:"status"`
+	ID     int          `json:"id,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
@@ -0,0 +1,35 @@
+package v1
+
+// Problem is an RFC 7807 problem details object
+// returned with the application/problem+json content type.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+	// Errors lists offending fields of a rejected request.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a field that broke a validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Stable problem codes.
+const (
+	ProblemBadRequest           = "bad_request"
+	ProblemNotFound             = "not_found"
+	ProblemConflict             = "conflict"
+	ProblemValidation           = "validation_failed"
+	ProblemPreconditionFailed   = "precondition_failed"
+	ProblemUnsupportedMediaType = "unsupported_media_type"
+	ProblemNotAcceptable        = "not_acceptable"
+	ProblemTimeout              = "timeout"
+	ProblemInternal             = "internal_error"
+)
@@ -0,0 +1,15 @@
+package v1
+
+// SearchResponse is a page of companies matching a search best first.
+type SearchResponse struct {
+	Items []SearchHit `json:"items"`
+	Links Links       `json:"links"`
+}
+
+// SearchHit is a matching company along with its rank and the name
+// with the matched words wrapped in <mark> and </mark>.
+type SearchHit struct {
+	Company
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
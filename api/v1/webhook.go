@@ -0,0 +1,47 @@
+package v1
+
+import "time"
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is generated on creation and kept on update if empty.
+	Secret string `json:"secret"`
+	// Active defaults to true.
+	Active *bool `json:"active"`
+}
+
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Failures  int       `json:"failures"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CreatedWebhook is returned once on webhook creation, Secret holds the signing secret.
+type CreatedWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhooksListResponse struct {
+	Items []Webhook `json:"items"`
+}
+
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	EventID    int64     `json:"eventId"`
+	EventType  string    `json:"eventType"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Items []WebhookDelivery `json:"items"`
+}
@@ -0,0 +1,20 @@
+-- Add company code and store phone as text.
+
+ALTER TABLE companies ADD COLUMN code VARCHAR (32) NOT NULL DEFAULT '';
+ALTER TABLE companies ALTER COLUMN phone TYPE VARCHAR (32) USING '';
+UPDATE companies SET phone = '' WHERE phone IS NULL;
+ALTER TABLE companies ALTER COLUMN phone SET NOT NULL;
+ALTER TABLE companies ALTER COLUMN phone SET DEFAULT '';
+
+CREATE INDEX companies_code_idx ON companies (code);
+CREATE INDEX companies_country_idx ON companies (country);
+
+---- create above / drop below ----
+
+DROP INDEX companies_country_idx;
+DROP INDEX companies_code_idx;
+
+ALTER TABLE companies ALTER COLUMN phone DROP DEFAULT;
+ALTER TABLE companies ALTER COLUMN phone DROP NOT NULL;
+ALTER TABLE companies ALTER COLUMN phone TYPE TIMESTAMP USING NULL;
+ALTER TABLE companies DROP COLUMN code;
@@ -0,0 +1,11 @@
+-- Indexes backing keyset pagination over common sort keys.
+
+CREATE INDEX companies_name_id_idx ON companies (name, id);
+CREATE INDEX companies_country_id_idx ON companies (country, id);
+DROP INDEX companies_country_idx;
+
+---- create above / drop below ----
+
+CREATE INDEX companies_country_idx ON companies (country);
+DROP INDEX companies_country_id_idx;
+DROP INDEX companies_name_id_idx;
@@ -0,0 +1,7 @@
+-- Company codes are unique when set.
+
+CREATE UNIQUE INDEX companies_code_key ON companies (code) WHERE code <> '';
+
+---- create above / drop below ----
+
+DROP INDEX companies_code_key;
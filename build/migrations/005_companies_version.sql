@@ -0,0 +1,20 @@
+-- Row version for optimistic concurrency control, bumped on every update.
+
+ALTER TABLE companies ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE FUNCTION companies_bump_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER companies_bump_version
+    BEFORE UPDATE ON companies
+    FOR EACH ROW EXECUTE FUNCTION companies_bump_version();
+
+---- create above / drop below ----
+
+DROP TRIGGER companies_bump_version ON companies;
+DROP FUNCTION companies_bump_version();
+ALTER TABLE companies DROP COLUMN version;
@@ -0,0 +1,17 @@
+-- Scoped API keys for machine clients. Only a SHA-256 hash of a key is stored.
+
+CREATE TABLE api_keys (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR (64) NOT NULL,
+        prefix VARCHAR (16) NOT NULL UNIQUE,
+        key_hash BYTEA NOT NULL,
+        scopes TEXT[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        expires_at TIMESTAMPTZ,
+        last_used_at TIMESTAMPTZ,
+        revoked_at TIMESTAMPTZ
+);
+
+---- create above / drop below ----
+
+DROP TABLE api_keys;
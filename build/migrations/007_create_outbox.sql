@@ -0,0 +1,17 @@
+-- Transactional outbox of company change events.
+
+CREATE TABLE outbox (
+        id BIGSERIAL PRIMARY KEY,
+        event_type VARCHAR (64) NOT NULL,
+        schema_version INTEGER NOT NULL,
+        aggregate_id INTEGER NOT NULL,
+        payload JSONB NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        published_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_unpublished_idx ON outbox (id) WHERE published_at IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE outbox;
@@ -0,0 +1,47 @@
+-- Webhook subscriptions, their pending delivery jobs and the delivery log.
+
+CREATE TABLE webhooks (
+        id SERIAL PRIMARY KEY,
+        url VARCHAR (2048) NOT NULL,
+        events TEXT[] NOT NULL,
+        secret VARCHAR (128) NOT NULL,
+        active BOOLEAN NOT NULL DEFAULT true,
+        failures INTEGER NOT NULL DEFAULT 0,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Finished jobs are kept, so that a replayed event is not delivered twice.
+CREATE TABLE webhook_jobs (
+        id BIGSERIAL PRIMARY KEY,
+        webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+        event_id BIGINT NOT NULL,
+        event_type VARCHAR (64) NOT NULL,
+        payload BYTEA NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        finished_at TIMESTAMPTZ,
+        UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX webhook_jobs_due_idx ON webhook_jobs (next_attempt_at) WHERE finished_at IS NULL;
+
+CREATE TABLE webhook_deliveries (
+        id BIGSERIAL PRIMARY KEY,
+        webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+        event_id BIGINT NOT NULL,
+        event_type VARCHAR (64) NOT NULL,
+        attempt INTEGER NOT NULL,
+        status_code INTEGER NOT NULL DEFAULT 0,
+        error TEXT NOT NULL DEFAULT '',
+        duration_ms INTEGER NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id);
+
+---- create above / drop below ----
+
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_jobs;
+DROP TABLE webhooks;
@@ -0,0 +1,30 @@
+-- Append-only audit trail of company changes. It has no foreign key,
+-- so that the history of a company outlives the company.
+
+CREATE TABLE company_audit (
+        id BIGSERIAL PRIMARY KEY,
+        company_id INTEGER NOT NULL,
+        actor VARCHAR (256) NOT NULL DEFAULT '',
+        request_id VARCHAR (128) NOT NULL DEFAULT '',
+        operation VARCHAR (16) NOT NULL,
+        changes JSONB NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX company_audit_company_idx ON company_audit (company_id, id);
+
+CREATE FUNCTION company_audit_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'company_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER company_audit_immutable
+    BEFORE UPDATE OR DELETE ON company_audit
+    FOR EACH ROW EXECUTE FUNCTION company_audit_immutable();
+
+---- create above / drop below ----
+
+DROP TRIGGER company_audit_immutable ON company_audit;
+DROP FUNCTION company_audit_immutable();
+DROP TABLE company_audit;
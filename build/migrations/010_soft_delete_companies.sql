@@ -0,0 +1,22 @@
+-- Soft delete of companies. Deleted companies free their code
+-- and are purged after the retention period.
+
+ALTER TABLE companies ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE companies ADD COLUMN deleted_by VARCHAR (256) NOT NULL DEFAULT '';
+
+DROP INDEX companies_code_key;
+CREATE UNIQUE INDEX companies_code_key ON companies (code) WHERE code <> '' AND deleted_at IS NULL;
+
+CREATE INDEX companies_deleted_at_idx ON companies (deleted_at) WHERE deleted_at IS NOT NULL;
+
+---- create above / drop below ----
+
+DELETE FROM companies WHERE deleted_at IS NOT NULL;
+
+DROP INDEX companies_deleted_at_idx;
+
+DROP INDEX companies_code_key;
+CREATE UNIQUE INDEX companies_code_key ON companies (code) WHERE code <> '';
+
+ALTER TABLE companies DROP COLUMN deleted_by;
+ALTER TABLE companies DROP COLUMN deleted_at;
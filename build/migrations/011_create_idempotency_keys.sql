@@ -0,0 +1,20 @@
+-- Responses recorded for idempotent POST requests, keyed by the principal
+-- and the client chosen key. A row with status 0 is claimed by a request being served.
+
+CREATE TABLE idempotency_keys (
+        principal VARCHAR (256) NOT NULL,
+        key VARCHAR (255) NOT NULL,
+        fingerprint VARCHAR (64) NOT NULL DEFAULT '',
+        status INTEGER NOT NULL DEFAULT 0,
+        header JSONB NOT NULL DEFAULT '{}',
+        body BYTEA NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        expires_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (principal, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+---- create above / drop below ----
+
+DROP TABLE idempotency_keys;
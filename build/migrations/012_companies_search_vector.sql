@@ -0,0 +1,15 @@
+-- Full-text search over company names and codes. The simple configuration
+-- keeps names as they are instead of stemming them as English words.
+
+ALTER TABLE companies ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', name), 'A') ||
+        setweight(to_tsvector('simple', code), 'B')
+) STORED;
+
+CREATE INDEX companies_search_vector_idx ON companies USING GIN (search_vector);
+
+---- create above / drop below ----
+
+DROP INDEX companies_search_vector_idx;
+
+ALTER TABLE companies DROP COLUMN search_vector;
@@ -0,0 +1,9 @@
+-- Trigram index matching misspelled and partial company names.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX companies_name_trgm_idx ON companies USING GIN (name gin_trgm_ops);
+
+---- create above / drop below ----
+
+DROP INDEX companies_name_trgm_idx;
@@ -23,13 +23,16 @@ func main() {
 	check("init config", err)
 
 	c := di.New("server", conf)
-	if conf.Database.Migration.Enabled {
+	needsDB, err := c.DatabaseRequired()
+	check("check database driver", err)
+
+	if conf.Database.Migration.Enabled && needsDB {
 		err = migrateDB(c)
 		check("migrate db", err)
 
 	}
 
-	app, err := initAPI(c)
+	app, err := initAPI(c, conf)
 	check("init api", err)
 
 	errCh := make(chan error, 1)
@@ -43,6 +46,41 @@ func main() {
 		cancel()
 	}()
 
+	if conf.Outbox.Enabled || conf.Webhooks.Enabled {
+		relay, err := c.OutboxRelay()
+		check("init outbox relay", err)
+
+		go relay.Run(ctx)
+	}
+
+	if conf.Webhooks.Enabled {
+		worker, err := c.WebhookWorker()
+		check("init webhook worker", err)
+
+		go worker.Run(ctx)
+	}
+
+	if conf.Purge.Enabled {
+		purger, err := c.Purger()
+		check("init purger", err)
+
+		go purger.Run(ctx)
+	}
+
+	if len(conf.Database.Replicas.URIs) > 0 && conf.Database.Driver != "memory" {
+		replicas, err := c.ReplicaSet()
+		check("init replicas", err)
+
+		go replicas.Run(ctx)
+	}
+
+	if conf.Cache.Enabled {
+		cache, err := c.CompanyCache()
+		check("init company cache", err)
+
+		go cache.Run(ctx)
+	}
+
 	go serveAPI(ctx, conf, app)
 
 	check("got signal", <-errCh)
@@ -86,7 +124,7 @@ func migrateDB(c *di.Container) error {
 	return nil
 }
 
-func initAPI(c *di.Container) (*api.API, error) {
+func initAPI(c *di.Container, conf config.Config) (*api.API, error) {
 	companyService, err := c.CompanyService()
 	if err != nil {
 		return nil, fmt.Errorf("new compane repo: %w", err)
@@ -97,6 +135,87 @@ func initAPI(c *di.Container) (*api.API, error) {
 		Logger:         c.Logger().WithName("apicompany"),
 		CompanyService: companyService,
 	}
+	// first, so that the problems written by the other middlewares carry the request id
+	a.Server.AddMiddleware(httpserver.RequestIDMiddleware)
+
+	if conf.Cache.Enabled {
+		cache, err := c.CompanyCache()
+		if err != nil {
+			return nil, fmt.Errorf("company cache: %w", err)
+		}
+		a.CompanyCacheStats = func() (uint64, uint64) {
+			stats := cache.Stats()
+			return stats.Hits, stats.Misses
+		}
+	}
+
+	routeTimeouts := make([]httpserver.RouteTimeout, 0, len(conf.Server.RouteTimeouts))
+	for _, rt := range conf.Server.RouteTimeouts {
+		routeTimeouts = append(routeTimeouts, httpserver.RouteTimeout(rt))
+	}
+	a.Server.AddMiddleware(httpserver.Timeout(conf.Server.RequestTimeout, routeTimeouts))
+
+	if conf.APIKeys.Enabled {
+		a.APIKeyService, err = c.APIKeyService()
+		if err != nil {
+			return nil, fmt.Errorf("new api key service: %w", err)
+		}
+
+		// let requests without a key fall through to the bearer authentication
+		keyAuth := httpserver.APIKeyAuth(a, api.APIKeyScope, conf.Auth.Enabled)
+		a.Server.AddMiddleware(keyAuth, httpserver.PathPrefix("/v1/companies"))
+		a.Server.AddMiddleware(keyAuth, httpserver.PathPrefix("/v1/apikeys"))
+		a.Server.AddMiddleware(keyAuth, httpserver.PathPrefix("/v1/webhooks"))
+	}
+
+	if conf.Webhooks.Enabled {
+		a.WebhookService, err = c.WebhookService()
+		if err != nil {
+			return nil, fmt.Errorf("new webhook service: %w", err)
+		}
+	}
+
+	if conf.Auth.Enabled {
+		verifier, err := c.TokenVerifier()
+		if err != nil {
+			return nil, fmt.Errorf("token verifier: %w", err)
+		}
+		a.Server.AddMiddleware(httpserver.BearerAuth(verifier), httpserver.PathPrefix(conf.Auth.PathPrefix))
+		// administration requires a token granting the admin scope of the route,
+		// e.g. restoring companies or reading deleted ones
+		a.Server.AddMiddleware(httpserver.TokenScope(api.AdminScope), httpserver.PathPrefix("/v1/companies"))
+		if conf.APIKeys.Enabled {
+			a.Server.AddMiddleware(httpserver.BearerAuth(verifier), httpserver.PathPrefix("/v1/apikeys"))
+			a.Server.AddMiddleware(httpserver.TokenScope(api.AdminScope), httpserver.PathPrefix("/v1/apikeys"))
+		}
+		if conf.Webhooks.Enabled {
+			a.Server.AddMiddleware(httpserver.BearerAuth(verifier), httpserver.PathPrefix("/v1/webhooks"))
+			a.Server.AddMiddleware(httpserver.TokenScope(api.AdminScope), httpserver.PathPrefix("/v1/webhooks"))
+		}
+	}
+
+	if len(conf.Geo.AllowedCountries) > 0 {
+		a.Server.AddMiddleware(
+			httpserver.CountryRestriction(c.GeoClient(), conf.Geo.AllowedCountries, conf.Geo.TrustForwarded),
+			httpserver.PathPrefix("/v1/companies"),
+		)
+	}
+
+	// keys are scoped by the principal set by the authentication above,
+	// responses issuing credentials are not recorded and imports are
+	// streamed rather than buffered
+	if conf.Idempotency.Enabled {
+		store, err := c.IdempotencyStore()
+		if err != nil {
+			return nil, fmt.Errorf("idempotency store: %w", err)
+		}
+		a.Server.AddMiddleware(
+			httpserver.Idempotency(store, conf.Idempotency.MaxBodySize, conf.Idempotency.LockWait),
+			httpserver.PathPrefix("/v1/companies"),
+			httpserver.ExceptPath("/v1/companies:import"),
+		)
+	}
+
 	a.Init()
 
 	return a, nil
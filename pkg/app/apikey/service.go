@@ -0,0 +1,140 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/apikey"
+)
+
+//go:generate mockgen -destination=./mocks/service.go -package=mocks . Service
+
+const (
+	keyPrefix    = "ak"
+	prefixBytes  = 6
+	secretBytes  = 32
+	maxNameLen   = 64
+	bootstrapKID = 0
+)
+
+type Service interface {
+	// Create issues a new key. The plaintext key is returned only here.
+	Create(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (key apikey.APIKey, plaintext string, err error)
+	List(ctx context.Context) ([]apikey.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	// Authenticate returns the active key matching the plaintext
+	// or apikey.ErrInvalidKey.
+	Authenticate(ctx context.Context, plaintext string) (apikey.APIKey, error)
+}
+
+type svc struct {
+	repo      apikey.Repository
+	bootstrap string
+}
+
+// NewService returns the API key service. If bootstrapKey is not empty
+// it authenticates as an admin key, so that the first keys can be created.
+func NewService(repo apikey.Repository, bootstrapKey string) Service {
+	return &svc{repo: repo, bootstrap: bootstrapKey}
+}
+
+func (s *svc) Create(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (apikey.APIKey, string, error) {
+	if name == "" || len(name) > maxNameLen {
+		return apikey.APIKey{}, "", fmt.Errorf("%w: must be 1 to %d characters long", apikey.ErrInvalidName, maxNameLen)
+	}
+	for _, scope := range scopes {
+		if !apikey.ValidScope(scope) {
+			return apikey.APIKey{}, "", fmt.Errorf("%w %q", apikey.ErrInvalidScope, scope)
+		}
+	}
+
+	prefix, err := randomString(prefixBytes)
+	if err != nil {
+		return apikey.APIKey{}, "", err
+	}
+	secret, err := randomString(secretBytes)
+	if err != nil {
+		return apikey.APIKey{}, "", err
+	}
+	plaintext := keyPrefix + "_" + prefix + "_" + secret
+
+	key := apikey.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Add(ctx, &key); err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("add api key: %w", err)
+	}
+
+	return key, plaintext, nil
+}
+
+func (s *svc) List(ctx context.Context) ([]apikey.APIKey, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	return list, nil
+}
+
+func (s *svc) Revoke(ctx context.Context, id int) error {
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *svc) Authenticate(ctx context.Context, plaintext string) (apikey.APIKey, error) {
+	if s.bootstrap != "" && subtle.ConstantTimeCompare([]byte(plaintext), []byte(s.bootstrap)) == 1 {
+		return apikey.APIKey{ID: bootstrapKID, Name: "bootstrap", Scopes: []string{apikey.ScopeAPIKeysAdmin}}, nil
+	}
+
+	parts := strings.Split(plaintext, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix {
+		return apikey.APIKey{}, apikey.ErrInvalidKey
+	}
+
+	key, err := s.repo.GetByPrefix(ctx, parts[1])
+	if errors.Is(err, apikey.ErrNotFound) {
+		return apikey.APIKey{}, apikey.ErrInvalidKey
+	}
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare(key.Hash, hash(plaintext)) != 1 || !key.Active(time.Now()) {
+		return apikey.APIKey{}, apikey.ErrInvalidKey
+	}
+
+	if err := s.repo.Touch(ctx, key.ID); err != nil {
+		return apikey.APIKey{}, fmt.Errorf("touch api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func hash(plaintext string) []byte {
+	sum := sha256.Sum256([]byte(plaintext))
+	return sum[:]
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,76 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	app "github.com/nyzhehorodov/apicompanies/pkg/app/apikey"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/apikey"
+	mocks "github.com/nyzhehorodov/apicompanies/pkg/domain/apikey/mocks"
+)
+
+type ctxKey struct{}
+
+func TestServiceCreateAuthenticate(t *testing.T) {
+	repo := mocks.NewMockRepository(gomock.NewController(t))
+	svc := app.NewService(repo, "")
+	// the repository gets the context of the request
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	var stored apikey.APIKey
+	repo.EXPECT().Add(ctx, gomock.Any()).DoAndReturn(func(_ interface{}, k *apikey.APIKey) error {
+		k.ID = 1
+		stored = *k
+		return nil
+	})
+
+	key, plaintext, err := svc.Create(ctx, "partner", []string{apikey.ScopeCompaniesRead}, nil)
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if key.ID != 1 || plaintext == "" {
+		t.Fatalf(`unexpected key %+v, plaintext %q`, key, plaintext)
+	}
+
+	repo.EXPECT().GetByPrefix(ctx, stored.Prefix).Return(stored, nil).Times(2)
+	repo.EXPECT().Touch(ctx, 1).Return(nil)
+
+	got, err := svc.Authenticate(ctx, plaintext)
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if !got.HasScope(apikey.ScopeCompaniesRead) {
+		t.Fatalf(`expected scope %q, got %v`, apikey.ScopeCompaniesRead, got.Scopes)
+	}
+
+	if _, err := svc.Authenticate(ctx, plaintext + "x"); !errors.Is(err, apikey.ErrInvalidKey) {
+		t.Fatalf(`expected error %v, got %v`, apikey.ErrInvalidKey, err)
+	}
+}
+
+func TestServiceAuthenticateExpired(t *testing.T) {
+	repo := mocks.NewMockRepository(gomock.NewController(t))
+	svc := app.NewService(repo, "")
+
+	var stored apikey.APIKey
+	repo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, k *apikey.APIKey) error {
+		stored = *k
+		return nil
+	})
+
+	expired := time.Now().Add(-time.Minute)
+	_, plaintext, err := svc.Create(context.Background(), "partner", nil, &expired)
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+
+	repo.EXPECT().GetByPrefix(gomock.Any(), stored.Prefix).Return(stored, nil)
+
+	if _, err := svc.Authenticate(context.Background(), plaintext); !errors.Is(err, apikey.ErrInvalidKey) {
+		t.Fatalf(`expected error %v, got %v`, apikey.ErrInvalidKey, err)
+	}
+}
@@ -0,0 +1,85 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// MaxBatchSize limits the number of operations of a batch.
+const MaxBatchSize = 1000
+
+// errBatchRollback rolls back an atomic batch with a failed operation.
+var errBatchRollback = errors.New("batch rolled back")
+
+func (s *svc) Batch(ctx context.Context, ops []company.Operation, atomic bool) (company.BatchReport, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchSize {
+		return company.BatchReport{}, &company.ValidationError{
+			Detail: fmt.Sprintf("batch must have 1 to %d operations, got %d", MaxBatchSize, len(ops)),
+		}
+	}
+
+	report := company.BatchReport{Results: make([]company.OperationResult, len(ops))}
+
+	if !atomic {
+		for i, op := range ops {
+			report.Results[i] = s.apply(ctx, op)
+		}
+		report.Committed = true
+
+		return report, nil
+	}
+
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		for i, op := range ops {
+			report.Results[i] = s.apply(ctx, op)
+			if report.Results[i].Err != nil {
+				return errBatchRollback
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchRollback) {
+		for i, res := range report.Results {
+			if res.Status != company.BatchFailed {
+				report.Results[i] = company.OperationResult{Status: company.BatchAborted}
+			}
+		}
+		return report, nil
+	}
+	if err != nil {
+		return company.BatchReport{}, fmt.Errorf("batch tx: %w", err)
+	}
+	report.Committed = true
+
+	return report, nil
+}
+
+// apply runs a single operation the same way as the matching method of the service.
+func (s *svc) apply(ctx context.Context, op company.Operation) company.OperationResult {
+	var (
+		c   company.Company
+		err error
+	)
+
+	switch op.Op {
+	case company.OpCreate:
+		c = op.Company
+		err = s.Add(ctx, &c)
+	case company.OpUpdate:
+		c = op.Company
+		c.ID, c.Version = op.ID, op.Version
+		err = s.Update(ctx, &c)
+	case company.OpDelete:
+		err = s.Delete(ctx, op.ID, op.Version)
+	default:
+		err = &company.ValidationError{Detail: fmt.Sprintf("unknown operation %q", op.Op)}
+	}
+	if err != nil {
+		return company.OperationResult{Status: company.BatchFailed, Err: err}
+	}
+
+	return company.OperationResult{Status: company.BatchApplied, Company: c}
+}
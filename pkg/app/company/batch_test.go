@@ -0,0 +1,114 @@
+package company_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	app "github.com/nyzhehorodov/apicompanies/pkg/app/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	mocks "github.com/nyzhehorodov/apicompanies/pkg/domain/company/mocks"
+	txmocks "github.com/nyzhehorodov/apicompanies/pkg/domain/transaction/mocks"
+)
+
+type txKey struct{}
+
+// ctxValue matches contexts carrying txKey.
+type ctxValue struct{}
+
+func (ctxValue) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && ctx.Value(txKey{}) != nil
+}
+
+func (ctxValue) String() string {
+	return "is a transaction context"
+}
+
+func TestServiceBatch(t *testing.T) {
+	ops := []company.Operation{
+		{Op: company.OpCreate, Company: company.Company{Name: "Acme", Country: "US"}},
+		{Op: company.OpDelete, ID: 3, Version: 2},
+		{Op: company.OpUpdate, ID: 4, Company: company.Company{Name: "Beta", Country: "GB"}},
+	}
+
+	tests := []struct {
+		name          string
+		atomic        bool
+		wantStatus    []string
+		wantCommitted bool
+	}{
+		{
+			name:       "atomic",
+			atomic:     true,
+			wantStatus: []string{company.BatchAborted, company.BatchFailed, company.BatchAborted},
+		},
+		{
+			name:          "best effort",
+			wantStatus:    []string{company.BatchApplied, company.BatchFailed, company.BatchApplied},
+			wantCommitted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mocks.NewMockRepository(ctrl)
+			txm := txmocks.NewMockManager(ctrl)
+
+			// repositories get the context of the transaction
+			txCtx := context.WithValue(context.Background(), txKey{}, true)
+			inTx := gomock.Any()
+			if tt.atomic {
+				inTx = ctxValue{}
+				txm.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+					return fn(txCtx)
+				})
+			}
+
+			repo.EXPECT().Add(inTx, gomock.Any()).DoAndReturn(func(_ context.Context, c *company.Company) error {
+				c.ID = 7
+				return nil
+			})
+			repo.EXPECT().Delete(inTx, 3, 2).Return(&company.PreconditionFailedError{Detail: "stale"})
+			if !tt.atomic {
+				repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			report, err := app.NewService(repo, txm).Batch(context.Background(), ops, tt.atomic)
+			if err != nil {
+				t.Fatalf(`unexpected error %v`, err)
+			}
+			if report.Committed != tt.wantCommitted {
+				t.Fatalf(`expected committed %v, got %v`, tt.wantCommitted, report.Committed)
+			}
+			for i, res := range report.Results {
+				if res.Status != tt.wantStatus[i] {
+					t.Fatalf(`expected status %q of operation %d, got %q`, tt.wantStatus[i], i, res.Status)
+				}
+			}
+
+			var precondition *company.PreconditionFailedError
+			if !errors.As(report.Results[1].Err, &precondition) {
+				t.Fatalf(`expected precondition error, got %v`, report.Results[1].Err)
+			}
+			if tt.atomic && report.Results[0].Company.ID != 0 {
+				t.Fatalf(`expected no company for a rolled back create, got %+v`, report.Results[0].Company)
+			}
+			if !tt.atomic && report.Results[0].Company.ID != 7 {
+				t.Fatalf(`expected created company 7, got %+v`, report.Results[0].Company)
+			}
+		})
+	}
+}
+
+func TestServiceBatchEmpty(t *testing.T) {
+	repo := mocks.NewMockRepository(gomock.NewController(t))
+
+	var validation *company.ValidationError
+	if _, err := app.NewService(repo, nil).Batch(context.Background(), nil, true); !errors.As(err, &validation) {
+		t.Fatalf(`expected validation error, got %v`, err)
+	}
+}
@@ -0,0 +1,88 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+// PurgeActor is the audit trail actor of purged companies.
+const PurgeActor = "system:purge"
+
+const defaultPurgeBatch = 1000
+
+type PurgerConfig struct {
+	// Retention is how long deleted companies are kept, it is required.
+	Retention time.Duration
+	// Interval between purge runs.
+	Interval time.Duration
+	// BatchSize limits the companies removed in a single statement.
+	BatchSize int
+}
+
+// Purger removes soft-deleted companies after the retention period.
+type Purger struct {
+	repo   company.Repository
+	logger log.Interface
+	conf   PurgerConfig
+}
+
+// NewPurger rejects a missing retention, which would purge companies
+// as soon as they are deleted and leave no time to restore them.
+func NewPurger(repo company.Repository, logger log.Interface, conf PurgerConfig) (*Purger, error) {
+	if conf.Retention <= 0 {
+		return nil, fmt.Errorf("purge retention must be positive, got %s", conf.Retention)
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = time.Hour
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaultPurgeBatch
+	}
+
+	return &Purger{repo: repo, logger: logger, conf: conf}, nil
+}
+
+// Run purges companies every interval until the context is canceled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			p.logger.Error(err, "purge deleted companies")
+		}
+		if n > 0 {
+			p.logger.Info("purged deleted companies", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes all companies deleted before the retention period
+// in batches and returns the number of removed companies.
+func (p *Purger) Purge(ctx context.Context) (int, error) {
+	ctx = company.WithActor(ctx, company.Actor{ID: PurgeActor})
+	before := time.Now().Add(-p.conf.Retention)
+
+	var total int
+	for {
+		n, err := p.repo.Purge(ctx, before, p.conf.BatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < p.conf.BatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package company_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	app "github.com/nyzhehorodov/apicompanies/pkg/app/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	mocks "github.com/nyzhehorodov/apicompanies/pkg/domain/company/mocks"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+func TestPurgerPurge(t *testing.T) {
+	repo := mocks.NewMockRepository(gomock.NewController(t))
+	purger, err := app.NewPurger(repo, log.Logger, app.PurgerConfig{Retention: 24 * time.Hour, BatchSize: 2})
+	if err != nil {
+		t.Fatalf(`NewPurger() error = %v`, err)
+	}
+
+	check := func(ctx context.Context, before time.Time) {
+		if actor := company.ActorFrom(ctx); actor.ID != app.PurgeActor {
+			t.Fatalf(`expected actor %q, got %q`, app.PurgeActor, actor.ID)
+		}
+		if age := time.Since(before); age < 24*time.Hour || age > 25*time.Hour {
+			t.Fatalf(`expected retention of a day, got %v`, age)
+		}
+	}
+
+	// full batches are followed by another one until a short batch
+	gomock.InOrder(
+		repo.EXPECT().Purge(gomock.Any(), gomock.Any(), 2).DoAndReturn(func(ctx context.Context, before time.Time, _ int) (int, error) {
+			check(ctx, before)
+			return 2, nil
+		}),
+		repo.EXPECT().Purge(gomock.Any(), gomock.Any(), 2).Return(1, nil),
+	)
+
+	n, err := purger.Purge(context.Background())
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if n != 3 {
+		t.Fatalf(`expected 3 purged companies, got %d`, n)
+	}
+}
+
+func TestNewPurgerRetention(t *testing.T) {
+	repo := mocks.NewMockRepository(gomock.NewController(t))
+
+	for _, retention := range []time.Duration{0, -time.Hour} {
+		if _, err := app.NewPurger(repo, log.Logger, app.PurgerConfig{Retention: retention}); err == nil {
+			t.Fatalf(`NewPurger() with retention %s error = nil, want an error`, retention)
+		}
+	}
+}
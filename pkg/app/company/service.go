@@ -3,56 +3,197 @@ package company
 import (
 	"context"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/transaction"
 )
 
 //go:generate mockgen -destination=./mocks/service.go -package=mocks . Service
 
+// Service manages companies. The context bounds the work of a call,
+// for mutations it also carries the actor recorded in the audit trail,
+// see company.WithActor.
 type Service interface {
-	Add(company company.Company) error
-	List(options company.ListOptions) (list []company.Company, count int, err error)
-	Update(company *company.Company) error
-	Delete(id int) error
+	Add(ctx context.Context, company *company.Company) error
+	Get(ctx context.Context, id int, options company.GetOptions) (company.Company, error)
+	List(ctx context.Context, options company.ListOptions) (page company.Page, total int, err error)
+	// Search finds companies by a partial or misspelled name or code.
+	Search(ctx context.Context, options company.SearchOptions) (company.SearchPage, error)
+	Update(ctx context.Context, company *company.Company) error
+	// Patch and Delete apply only if version is zero or matches
+	// the stored one, Update checks company.Version the same way.
+	Patch(ctx context.Context, id, version int, patch company.Patch) (company.Company, error)
+	// Delete soft-deletes the company, Restore undeletes it.
+	Delete(ctx context.Context, id, version int) error
+	Restore(ctx context.Context, id, version int) (company.Company, error)
+	// Export streams the companies matching the options to fn,
+	// see company.Repository.Export.
+	Export(ctx context.Context, options company.ListOptions, fn func(company.Company) error) error
+	// Import creates companies from the rows of the source, rows are
+	// validated as single creates. See company.Repository.Import.
+	Import(ctx context.Context, source company.ImportSource, atomic bool) (company.ImportReport, error)
+	// Batch applies the operations in order. An atomic batch runs them
+	// in a single transaction and stops at the first failure, rolling back
+	// the operations already applied. Otherwise every operation is
+	// committed on its own and failures do not stop the batch.
+	Batch(ctx context.Context, ops []company.Operation, atomic bool) (company.BatchReport, error)
+	// History returns the audit trail of the company.
+	History(ctx context.Context, id int, options company.HistoryOptions) (company.HistoryPage, error)
 }
 
+// maxSearchLen limits the length of a search query.
+const maxSearchLen = 256
+
 type svc struct {
 	repo company.Repository
+	tx   transaction.Manager
 }
 
-func NewService(repo company.Repository) Service {
-	return &svc{repo: repo}
+func NewService(repo company.Repository, tx transaction.Manager) Service {
+	return &svc{repo: repo, tx: tx}
 }
 
-func (s *svc) Add(company company.Company) error {
-	if err := s.repo.Add(context.Background(), company); err != nil {
+func (s *svc) Add(ctx context.Context, company *company.Company) error {
+	if err := Validate(*company); err != nil {
+		return err
+	}
+
+	if err := s.repo.Add(ctx, company); err != nil {
 		return fmt.Errorf("add company: %w", err)
 	}
 
 	return nil
 }
 
-func (s *svc) List(options company.ListOptions) ([]company.Company, int, error) {
-	list, err := s.repo.List(context.Background(), options)
+func (s *svc) Get(ctx context.Context, id int, options company.GetOptions) (company.Company, error) {
+	c, err := s.repo.Get(ctx, id, options)
 	if err != nil {
-		return nil, 0, fmt.Errorf("list companies: %w", err)
+		return company.Company{}, fmt.Errorf("get company: %w", err)
 	}
 
-	return list, len(list), nil
+	return c, nil
 }
 
-func (s *svc) Update(company *company.Company) error {
-	if err := s.repo.Update(context.Background(), company); err != nil {
+func (s *svc) List(ctx context.Context, options company.ListOptions) (company.Page, int, error) {
+	page, err := s.repo.List(ctx, options)
+	if err != nil {
+		return company.Page{}, 0, fmt.Errorf("list companies: %w", err)
+	}
+
+	total, err := s.repo.Count(ctx, options)
+	if err != nil {
+		return company.Page{}, 0, fmt.Errorf("count companies: %w", err)
+	}
+
+	return page, total, nil
+}
+
+func (s *svc) Search(ctx context.Context, options company.SearchOptions) (company.SearchPage, error) {
+	options.Query = strings.TrimSpace(options.Query)
+	if options.Query == "" || utf8.RuneCountInString(options.Query) > maxSearchLen {
+		return company.SearchPage{}, &company.ValidationError{
+			Detail: fmt.Sprintf("search query must have 1 to %d characters", maxSearchLen),
+		}
+	}
+
+	page, err := s.repo.Search(ctx, options)
+	if err != nil {
+		return company.SearchPage{}, fmt.Errorf("search companies: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *svc) Update(ctx context.Context, company *company.Company) error {
+	if err := Validate(*company); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(ctx, company); err != nil {
 		return fmt.Errorf("update company: %w", err)
 	}
 
 	return nil
 }
 
-func (s *svc) Delete(id int) error {
-	if err := s.repo.Delete(context.Background(), id); err != nil {
+func (s *svc) Patch(ctx context.Context, id, version int, patch company.Patch) (company.Company, error) {
+	current, err := s.repo.Get(ctx, id, company.GetOptions{})
+	if err != nil {
+		return company.Company{}, fmt.Errorf("get company: %w", err)
+	}
+	if version != 0 && current.Version != version {
+		return company.Company{}, &company.PreconditionFailedError{
+			Detail: fmt.Sprintf("company %d is at version %d, expected %d", id, current.Version, version),
+		}
+	}
+
+	if err := Validate(patch.Apply(current)); err != nil {
+		return company.Company{}, err
+	}
+
+	patched, err := s.repo.Patch(ctx, id, version, patch)
+	if err != nil {
+		return company.Company{}, fmt.Errorf("patch company: %w", err)
+	}
+
+	return patched, nil
+}
+
+func (s *svc) Delete(ctx context.Context, id, version int) error {
+	if err := s.repo.Delete(ctx, id, version); err != nil {
 		return fmt.Errorf("delete company: %w", err)
 	}
 
 	return nil
 }
+
+func (s *svc) Restore(ctx context.Context, id, version int) (company.Company, error) {
+	c, err := s.repo.Restore(ctx, id, version)
+	if err != nil {
+		return company.Company{}, fmt.Errorf("restore company: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *svc) Export(ctx context.Context, options company.ListOptions, fn func(company.Company) error) error {
+	if err := s.repo.Export(ctx, options, fn); err != nil {
+		return fmt.Errorf("export companies: %w", err)
+	}
+
+	return nil
+}
+
+func (s *svc) Import(ctx context.Context, source company.ImportSource, atomic bool) (company.ImportReport, error) {
+	report, err := s.repo.Import(ctx, validatingSource{source}, atomic)
+	if err != nil {
+		return company.ImportReport{}, fmt.Errorf("import companies: %w", err)
+	}
+
+	return report, nil
+}
+
+// validatingSource rejects the rows of an import that fail Validate.
+type validatingSource struct {
+	company.ImportSource
+}
+
+func (s validatingSource) Row() company.ImportRow {
+	row := s.ImportSource.Row()
+	if row.Err == nil {
+		row.Err = Validate(row.Company)
+	}
+
+	return row
+}
+
+func (s *svc) History(ctx context.Context, id int, options company.HistoryOptions) (company.HistoryPage, error) {
+	page, err := s.repo.History(ctx, id, options)
+	if err != nil {
+		return company.HistoryPage{}, fmt.Errorf("company history: %w", err)
+	}
+
+	return page, nil
+}
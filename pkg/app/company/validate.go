@@ -0,0 +1,132 @@
+package company
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// Field length limits, they match the companies table.
+const (
+	maxCodeLen    = 32
+	maxNameLen    = 64
+	maxWebsiteLen = 256
+	maxPhoneLen   = 32
+)
+
+// Validation rules reported in company.FieldError.
+const (
+	RuleRequired = "required"
+	RuleMaxLen   = "max_length"
+	RuleFormat   = "format"
+	RuleCountry  = "iso3166_alpha2"
+	RuleURL      = "http_url"
+	RulePhone    = "e164"
+)
+
+var (
+	codeRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	phoneRe = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+// validator collects field errors of a single company.
+type validator struct {
+	errs []company.FieldError
+}
+
+func (v *validator) fail(field, rule, msg string) {
+	v.errs = append(v.errs, company.FieldError{Field: field, Rule: rule, Message: msg})
+}
+
+// maxLen reports whether the value fits into the limit.
+func (v *validator) maxLen(field, val string, limit int) bool {
+	if utf8.RuneCountInString(val) > limit {
+		v.fail(field, RuleMaxLen, fmt.Sprintf("must be at most %d characters long", limit))
+		return false
+	}
+
+	return true
+}
+
+// Validate checks company fields and returns a *company.ValidationError
+// listing every offending field, or nil if the company is valid.
+// Name and country are required, code, website and phone are optional.
+func Validate(c company.Company) error {
+	v := &validator{}
+
+	if c.Code != "" && v.maxLen("code", c.Code, maxCodeLen) && !codeRe.MatchString(c.Code) {
+		v.fail("code", RuleFormat, "must contain only letters, digits, '-' and '_' and start with a letter or digit")
+	}
+
+	if c.Name == "" {
+		v.fail("name", RuleRequired, "is required")
+	} else {
+		v.maxLen("name", c.Name, maxNameLen)
+	}
+
+	if c.Country == "" {
+		v.fail("country", RuleRequired, "is required")
+	} else if !isCountryCode(c.Country) {
+		v.fail("country", RuleCountry, "must be an ISO 3166-1 alpha-2 country code")
+	}
+
+	if c.Website != "" && v.maxLen("website", c.Website, maxWebsiteLen) && !isHTTPURL(c.Website) {
+		v.fail("website", RuleURL, "must be an absolute http or https URL")
+	}
+
+	if c.Phone != "" && v.maxLen("phone", c.Phone, maxPhoneLen) && !phoneRe.MatchString(c.Phone) {
+		v.fail("phone", RulePhone, "must be an E.164 phone number, e.g. +35722123456")
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return &company.ValidationError{Detail: "company is invalid", Fields: v.errs}
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isCountryCode(s string) bool {
+	_, ok := countryCodes[s]
+	return ok
+}
+
+// countryCodes holds ISO 3166-1 alpha-2 officially assigned codes.
+var countryCodes = map[string]struct{}{
+	"AD": {}, "AE": {}, "AF": {}, "AG": {}, "AI": {}, "AL": {}, "AM": {}, "AO": {}, "AQ": {}, "AR": {},
+	"AS": {}, "AT": {}, "AU": {}, "AW": {}, "AX": {}, "AZ": {}, "BA": {}, "BB": {}, "BD": {}, "BE": {},
+	"BF": {}, "BG": {}, "BH": {}, "BI": {}, "BJ": {}, "BL": {}, "BM": {}, "BN": {}, "BO": {}, "BQ": {},
+	"BR": {}, "BS": {}, "BT": {}, "BV": {}, "BW": {}, "BY": {}, "BZ": {}, "CA": {}, "CC": {}, "CD": {},
+	"CF": {}, "CG": {}, "CH": {}, "CI": {}, "CK": {}, "CL": {}, "CM": {}, "CN": {}, "CO": {}, "CR": {},
+	"CU": {}, "CV": {}, "CW": {}, "CX": {}, "CY": {}, "CZ": {}, "DE": {}, "DJ": {}, "DK": {}, "DM": {},
+	"DO": {}, "DZ": {}, "EC": {}, "EE": {}, "EG": {}, "EH": {}, "ER": {}, "ES": {}, "ET": {}, "FI": {},
+	"FJ": {}, "FK": {}, "FM": {}, "FO": {}, "FR": {}, "GA": {}, "GB": {}, "GD": {}, "GE": {}, "GF": {},
+	"GG": {}, "GH": {}, "GI": {}, "GL": {}, "GM": {}, "GN": {}, "GP": {}, "GQ": {}, "GR": {}, "GS": {},
+	"GT": {}, "GU": {}, "GW": {}, "GY": {}, "HK": {}, "HM": {}, "HN": {}, "HR": {}, "HT": {}, "HU": {},
+	"ID": {}, "IE": {}, "IL": {}, "IM": {}, "IN": {}, "IO": {}, "IQ": {}, "IR": {}, "IS": {}, "IT": {},
+	"JE": {}, "JM": {}, "JO": {}, "JP": {}, "KE": {}, "KG": {}, "KH": {}, "KI": {}, "KM": {}, "KN": {},
+	"KP": {}, "KR": {}, "KW": {}, "KY": {}, "KZ": {}, "LA": {}, "LB": {}, "LC": {}, "LI": {}, "LK": {},
+	"LR": {}, "LS": {}, "LT": {}, "LU": {}, "LV": {}, "LY": {}, "MA": {}, "MC": {}, "MD": {}, "ME": {},
+	"MF": {}, "MG": {}, "MH": {}, "MK": {}, "ML": {}, "MM": {}, "MN": {}, "MO": {}, "MP": {}, "MQ": {},
+	"MR": {}, "MS": {}, "MT": {}, "MU": {}, "MV": {}, "MW": {}, "MX": {}, "MY": {}, "MZ": {}, "NA": {},
+	"NC": {}, "NE": {}, "NF": {}, "NG": {}, "NI": {}, "NL": {}, "NO": {}, "NP": {}, "NR": {}, "NU": {},
+	"NZ": {}, "OM": {}, "PA": {}, "PE": {}, "PF": {}, "PG": {}, "PH": {}, "PK": {}, "PL": {}, "PM": {},
+	"PN": {}, "PR": {}, "PS": {}, "PT": {}, "PW": {}, "PY": {}, "QA": {}, "RE": {}, "RO": {}, "RS": {},
+	"RU": {}, "RW": {}, "SA": {}, "SB": {}, "SC": {}, "SD": {}, "SE": {}, "SG": {}, "SH": {}, "SI": {},
+	"SJ": {}, "SK": {}, "SL": {}, "SM": {}, "SN": {}, "SO": {}, "SR": {}, "SS": {}, "ST": {}, "SV": {},
+	"SX": {}, "SY": {}, "SZ": {}, "TC": {}, "TD": {}, "TF": {}, "TG": {}, "TH": {}, "TJ": {}, "TK": {},
+	"TL": {}, "TM": {}, "TN": {}, "TO": {}, "TR": {}, "TT": {}, "TV": {}, "TW": {}, "TZ": {}, "UA": {},
+	"UG": {}, "UM": {}, "US": {}, "UY": {}, "UZ": {}, "VA": {}, "VC": {}, "VE": {}, "VG": {}, "VI": {},
+	"VN": {}, "VU": {}, "WF": {}, "WS": {}, "YE": {}, "YT": {}, "ZA": {}, "ZM": {}, "ZW": {},
+}
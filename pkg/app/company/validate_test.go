@@ -0,0 +1,53 @@
+package company_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	app "github.com/nyzhehorodov/apicompanies/pkg/app/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func TestValidate(t *testing.T) {
+	valid := company.Company{
+		Code:    "ACME-1",
+		Name:    "Acme",
+		Country: "CY",
+		Website: "https://acme.example",
+		Phone:   "+35722123456",
+	}
+	if err := app.Validate(valid); err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+
+	invalid := company.Company{
+		Code:    "-acme",
+		Name:    strings.Repeat("a", 65),
+		Country: "XX",
+		Website: "ftp://acme.example",
+		Phone:   "22123456",
+	}
+
+	var verr *company.ValidationError
+	if err := app.Validate(invalid); !errors.As(err, &verr) {
+		t.Fatalf(`expected validation error, got %v`, err)
+	}
+
+	got := map[string]string{}
+	for _, f := range verr.Fields {
+		got[f.Field] = f.Rule
+	}
+
+	want := map[string]string{
+		"code":    app.RuleFormat,
+		"name":    app.RuleMaxLen,
+		"country": app.RuleCountry,
+		"website": app.RuleURL,
+		"phone":   app.RulePhone,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`expected field rules %v, got %v`, want, got)
+	}
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+)
+
+//go:generate mockgen -destination=./mocks/service.go -package=mocks . Service
+
+const (
+	secretPrefix  = "whsec_"
+	secretBytes   = 32
+	minSecretLen  = 16
+	maxSecretLen  = 128
+	maxURLLen     = 2048
+	maxDeliveries = 100
+	defDeliveries = 20
+)
+
+type Service interface {
+	// Create registers a webhook, a secret is generated if none is given.
+	Create(ctx context.Context, w *webhook.Webhook) error
+	Get(ctx context.Context, id int) (webhook.Webhook, error)
+	List(ctx context.Context) ([]webhook.Webhook, error)
+	// Update keeps the stored secret if none is given.
+	Update(ctx context.Context, w *webhook.Webhook) error
+	Delete(ctx context.Context, id int) error
+	// Deliveries returns the latest delivery attempts of the webhook.
+	Deliveries(ctx context.Context, id, limit int) ([]webhook.Delivery, error)
+}
+
+type svc struct {
+	repo webhook.Repository
+}
+
+func NewService(repo webhook.Repository) Service {
+	return &svc{repo: repo}
+}
+
+func (s *svc) Create(ctx context.Context, w *webhook.Webhook) error {
+	if w.Secret == "" {
+		secret, err := randomSecret()
+		if err != nil {
+			return err
+		}
+		w.Secret = secret
+	}
+
+	if err := validate(w); err != nil {
+		return err
+	}
+
+	if err := s.repo.Add(ctx, w); err != nil {
+		return fmt.Errorf("add webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (s *svc) Get(ctx context.Context, id int) (webhook.Webhook, error) {
+	w, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("get webhook: %w", err)
+	}
+
+	return w, nil
+}
+
+func (s *svc) List(ctx context.Context) ([]webhook.Webhook, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+
+	return list, nil
+}
+
+func (s *svc) Update(ctx context.Context, w *webhook.Webhook) error {
+	if w.Secret == "" {
+		current, err := s.Get(ctx, w.ID)
+		if err != nil {
+			return err
+		}
+		w.Secret = current.Secret
+	}
+
+	if err := validate(w); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(ctx, w); err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (s *svc) Delete(ctx context.Context, id int) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (s *svc) Deliveries(ctx context.Context, id, limit int) ([]webhook.Delivery, error) {
+	if limit <= 0 {
+		limit = defDeliveries
+	}
+	if limit > maxDeliveries {
+		limit = maxDeliveries
+	}
+
+	// report unknown webhooks rather than an empty log
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	list, err := s.repo.Deliveries(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+
+	return list, nil
+}
+
+func validate(w *webhook.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil || len(w.URL) > maxURLLen || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: must be an absolute http or https url", webhook.ErrInvalidURL)
+	}
+
+	if len(w.Events) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", webhook.ErrInvalidEvent)
+	}
+	for _, e := range w.Events {
+		if !webhook.ValidEvent(e) {
+			return fmt.Errorf("%w %q", webhook.ErrInvalidEvent, e)
+		}
+	}
+
+	if len(w.Secret) < minSecretLen || len(w.Secret) > maxSecretLen {
+		return fmt.Errorf("%w: must be %d to %d characters long", webhook.ErrInvalidSecret, minSecretLen, maxSecretLen)
+	}
+
+	return nil
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+
+	return secretPrefix + hex.EncodeToString(b), nil
+}
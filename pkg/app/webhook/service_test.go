@@ -0,0 +1,90 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	app "github.com/nyzhehorodov/apicompanies/pkg/app/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+	mocks "github.com/nyzhehorodov/apicompanies/pkg/domain/webhook/mocks"
+)
+
+func TestServiceCreate(t *testing.T) {
+	tests := []struct {
+		name string
+		hook webhook.Webhook
+		err  error
+	}{
+		{
+			name: "generated secret",
+			hook: webhook.Webhook{URL: "https://example.com/hook", Events: []string{"company.created"}},
+		},
+		{
+			name: "relative url",
+			hook: webhook.Webhook{URL: "/hook", Events: []string{"company.created"}},
+			err:  webhook.ErrInvalidURL,
+		},
+		{
+			name: "unsupported scheme",
+			hook: webhook.Webhook{URL: "ftp://example.com/hook", Events: []string{"company.created"}},
+			err:  webhook.ErrInvalidURL,
+		},
+		{
+			name: "no events",
+			hook: webhook.Webhook{URL: "https://example.com/hook"},
+			err:  webhook.ErrInvalidEvent,
+		},
+		{
+			name: "unknown event",
+			hook: webhook.Webhook{URL: "https://example.com/hook", Events: []string{"company.renamed"}},
+			err:  webhook.ErrInvalidEvent,
+		},
+		{
+			name: "short secret",
+			hook: webhook.Webhook{URL: "https://example.com/hook", Events: []string{"company.deleted"}, Secret: "short"},
+			err:  webhook.ErrInvalidSecret,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewMockRepository(gomock.NewController(t))
+			if tt.err == nil {
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			hook := tt.hook
+			err := app.NewService(repo).Create(context.Background(), &hook)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf(`expected error %v, got %v`, tt.err, err)
+			}
+			if err == nil && !strings.HasPrefix(hook.Secret, "whsec_") {
+				t.Fatalf(`expected generated secret, got %q`, hook.Secret)
+			}
+		})
+	}
+}
+
+type ctxKey struct{}
+
+func TestServiceUpdateKeepsSecret(t *testing.T) {
+	repo := mocks.NewMockRepository(gomock.NewController(t))
+	// the repository gets the context of the request
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	stored := webhook.Webhook{ID: 1, URL: "https://example.com/hook", Events: []string{"company.updated"}, Secret: "whsec_0123456789abcdef"}
+	repo.EXPECT().Get(ctx, 1).Return(stored, nil)
+	repo.EXPECT().Update(ctx, gomock.Any()).Return(nil)
+
+	hook := webhook.Webhook{ID: 1, URL: "https://example.com/other", Events: []string{"company.updated"}, Active: true}
+	if err := app.NewService(repo).Update(ctx, &hook); err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if hook.Secret != stored.Secret {
+		t.Fatalf(`expected secret %q, got %q`, stored.Secret, hook.Secret)
+	}
+}
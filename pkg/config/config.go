@@ -1,10 +1,20 @@
 package config
 
+import "time"
+
 // Config is an application config
 // Should be used only in main packages for config parsing and dependency initialization.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Geo         GeoConfig
+	Auth        AuthConfig
+	APIKeys     APIKeysConfig
+	Outbox      OutboxConfig
+	Webhooks    WebhooksConfig
+	Purge       PurgeConfig
+	Idempotency IdempotencyConfig
+	Cache       CacheConfig
 
 	Log LogConfig
 }
@@ -13,13 +23,45 @@ type ServerConfig struct {
 	Port    int
 	TLSKey  string
 	TLSCert string
+	// RequestTimeout cancels the work of a request, zero disables it.
+	RequestTimeout time.Duration
+	// RouteTimeouts override RequestTimeout for the routes
+	// starting with their prefixes, the longest prefix wins.
+	RouteTimeouts []RouteTimeoutConfig
+}
+
+type RouteTimeoutConfig struct {
+	PathPrefix string
+	Timeout    time.Duration
 }
 
 type DatabaseConfig struct {
-	URI       string
-	MinConns  int32
-	MaxConns  int32
-	Migration MigrationConfig
+	// Driver selects the company storage: "postgres", the default, or "memory".
+	// The memory one is meant for development, API keys and idempotency keys
+	// still use Postgres, the outbox and webhooks are not supported with it.
+	Driver string
+	// Fixtures is an optional JSON file seeding the memory storage at startup.
+	Fixtures    string
+	URI         string
+	MinConns    int32
+	MaxConns    int32
+	Migration   MigrationConfig
+	Transaction TransactionConfig
+	Replicas    ReplicasConfig
+}
+
+// ReplicasConfig configures the read replicas of the company storage,
+// reads go to the primary if there are none.
+type ReplicasConfig struct {
+	// URIs of the replicas, each gets a pool sized as the primary one.
+	URIs          []string
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+	// MaxLag is the replication lag dropping a replica from rotation, zero disables the check.
+	MaxLag time.Duration
+	// ReadYourWrites is how long the reads of an actor go to the primary
+	// after its write, zero disables it.
+	ReadYourWrites time.Duration
 }
 
 type MigrationConfig struct {
@@ -28,7 +70,127 @@ type MigrationConfig struct {
 	VersionTable string
 }
 
+// TransactionConfig configures transactions spanning several repository calls.
+type TransactionConfig struct {
+	// Isolation is one of "read committed", "repeatable read"
+	// and "serializable", empty means the server default.
+	Isolation string
+	// MaxAttempts of a transaction failing on serialization or deadlock.
+	MaxAttempts int
+	RetryDelay  time.Duration
+}
+
+// GeoConfig configures the ipapi.co client and the country restriction of mutations.
+type GeoConfig struct {
+	BaseURL  string
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	// AllowedCountries lists ISO 3166-1 alpha-2 codes allowed to create,
+	// update and delete companies. Empty list disables the restriction.
+	AllowedCountries []string
+	// TrustForwarded takes the client IP from X-Forwarded-For.
+	TrustForwarded bool
+}
+
+// AuthConfig configures JWT bearer authentication.
+type AuthConfig struct {
+	Enabled bool
+	// PathPrefix limits authentication to the matching routes.
+	PathPrefix string
+	Issuer     string
+	Audience   string
+	Leeway     time.Duration
+	// HMACSecretFile holds the HS256 shared secret.
+	HMACSecretFile string
+	// PublicKeyFiles hold PEM encoded RS256 or ES256 public keys.
+	PublicKeyFiles []string
+	// JWKSFile is a local JSON Web Key Set.
+	JWKSFile string
+}
+
+// APIKeysConfig configures scoped API key authentication.
+type APIKeysConfig struct {
+	Enabled bool
+	// BootstrapKey authenticates as an admin key allowed to manage API keys,
+	// it is meant to create the first keys and should be unset afterwards.
+	BootstrapKey string
+}
+
+// OutboxConfig configures the relay of company change events.
+type OutboxConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
+	// Retention is how long published events are kept in the outbox.
+	Retention time.Duration
+	// Publisher is either "log" or "http".
+	Publisher string
+	// URL receives the events of the http publisher.
+	URL     string
+	Timeout time.Duration
+}
+
+// WebhooksConfig configures webhook subscriptions and their delivery.
+// Deliveries are fed by the outbox relay, which runs whenever webhooks are enabled.
+type WebhooksConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
+	// Timeout of a single delivery request.
+	Timeout     time.Duration
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// DisableAfter consecutive failed attempts a webhook is disabled.
+	DisableAfter int
+}
+
+// PurgeConfig configures the removal of soft-deleted companies.
+type PurgeConfig struct {
+	Enabled bool
+	// Retention is how long deleted companies can be restored, required when enabled.
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// IdempotencyConfig configures the Idempotency-Key support of POST requests.
+type IdempotencyConfig struct {
+	Enabled bool
+	// TTL is how long a recorded response is replayed.
+	TTL time.Duration
+	// MaxBodySize limits the body of a request with an idempotency key.
+	MaxBodySize int64
+	// LockWait is how long a request waits for a concurrent one
+	// with the same key to finish before getting 409.
+	LockWait time.Duration
+}
+
 type LogConfig struct {
 	Development bool
 	Verbosity   int8
 }
+
+// CacheConfig configures the read-through cache of company reads.
+type CacheConfig struct {
+	Enabled bool
+	// Backend is either "lru", local to the process, or "redis", shared by the replicas.
+	Backend string
+	TTL     time.Duration
+	// Size limits the entries of the lru backend.
+	Size int
+	// KeyPrefix of the entries in the shared backend.
+	KeyPrefix string
+	// Channel is the Postgres LISTEN/NOTIFY channel of invalidations,
+	// empty keeps invalidations within the replica.
+	Channel string
+	Redis   RedisConfig
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	PoolSize int
+	Timeout  time.Duration
+}
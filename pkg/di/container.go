@@ -2,17 +2,30 @@ package di
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	zapoptions "go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/nyzhehorodov/apicompanies/pkg/app/apikey"
 	"github.com/nyzhehorodov/apicompanies/pkg/app/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/app/webhook"
 	"github.com/nyzhehorodov/apicompanies/pkg/config"
+	dapikey "github.com/nyzhehorodov/apicompanies/pkg/domain/apikey"
 	dcompany "github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/transaction"
+	dwebhook "github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/cache"
 	"github.com/nyzhehorodov/apicompanies/pkg/infra/db"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/ipapico"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/memory"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/outbox"
+	iwebhook "github.com/nyzhehorodov/apicompanies/pkg/infra/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/jwt"
 	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
 	"github.com/nyzhehorodov/apicompanies/pkg/lib/log/zap"
 	"github.com/nyzhehorodov/apicompanies/pkg/lib/migration"
@@ -21,12 +34,26 @@ import (
 // Container is a dependency injection container to be used in the main packages.
 // All common dependency initialization should go here.
 type Container struct {
-	name           string
-	conf           config.Config
-	log            log.Interface
-	connPool       *pgxpool.Pool
-	companyRepo    dcompany.Repository
-	companyService company.Service
+	name             string
+	conf             config.Config
+	log              log.Interface
+	connPool         *pgxpool.Pool
+	companyRepo      dcompany.Repository
+	companyService   company.Service
+	geoClient        *ipapico.Client
+	tokenVerifier    *jwt.Verifier
+	apiKeyRepo       dapikey.Repository
+	apiKeyService    apikey.Service
+	outboxRelay      *outbox.Relay
+	webhookRepo      dwebhook.Repository
+	webhookService   webhook.Service
+	webhookWorker    *iwebhook.Worker
+	purger           *company.Purger
+	idempotencyStore *db.IdempotencyPostgresStore
+	txManager        transaction.Manager
+	memoryRepo       *memory.CompanyRepository
+	companyCache     *cache.CompanyRepository
+	replicaSet       *db.ReplicaSet
 }
 
 func New(name string, conf config.Config) *Container {
@@ -58,7 +85,20 @@ func (c *Container) ConnPool() (*pgxpool.Pool, error) {
 		return c.connPool, nil
 	}
 
-	conf, err := pgxpool.ParseConfig(c.conf.Database.URI)
+	connPool, err := c.newPool(c.conf.Database.URI, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.connPool = connPool
+
+	return c.connPool, nil
+}
+
+// newPool connects a pool to the database. A lazy pool connects
+// on first use, so an unreachable database does not fail the startup.
+func (c *Container) newPool(uri string, lazy bool) (*pgxpool.Pool, error) {
+	conf, err := pgxpool.ParseConfig(uri)
 	if err != nil {
 		return nil, fmt.Errorf("pgx parse config: %w", err)
 	}
@@ -69,15 +109,47 @@ func (c *Container) ConnPool() (*pgxpool.Pool, error) {
 	if c.conf.Database.MaxConns > 0 {
 		conf.MaxConns = c.conf.Database.MaxConns
 	}
+	conf.LazyConnect = lazy
 
 	connPool, err := pgxpool.ConnectConfig(context.Background(), conf)
 	if err != nil {
 		return nil, fmt.Errorf("new pgx conn pool: %w", err)
 	}
 
-	c.connPool = connPool
+	return connPool, nil
+}
 
-	return c.connPool, nil
+// ReplicaSet returns the read replicas of the primary pool,
+// nil if there are none configured.
+func (c *Container) ReplicaSet() (*db.ReplicaSet, error) {
+	conf := c.conf.Database.Replicas
+	if c.replicaSet != nil || len(conf.URIs) == 0 {
+		return c.replicaSet, nil
+	}
+
+	primary, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	// replicas down at startup join the rotation once a health check passes
+	replicas := make([]*pgxpool.Pool, 0, len(conf.URIs))
+	for i, uri := range conf.URIs {
+		pool, err := c.newPool(uri, true)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		replicas = append(replicas, pool)
+	}
+
+	c.replicaSet = db.NewReplicaSet(primary, replicas, c.Logger().WithName("replicas"), db.ReplicaConfig{
+		CheckInterval:  conf.CheckInterval,
+		CheckTimeout:   conf.CheckTimeout,
+		MaxLag:         conf.MaxLag,
+		ReadYourWrites: conf.ReadYourWrites,
+	})
+
+	return c.replicaSet, nil
 }
 
 func (c *Container) NewMigrator() (migration.Migrator, error) {
@@ -111,22 +183,433 @@ func (c *Container) CompanyService() (company.Service, error) {
 		return nil, err
 	}
 
-	c.companyService = company.NewService(companyRepo)
+	txManager, err := c.TxManager()
+	if err != nil {
+		return nil, err
+	}
+
+	c.companyService = company.NewService(companyRepo, txManager)
 
 	return c.companyService, nil
 }
 
+func (c *Container) TxManager() (transaction.Manager, error) {
+	if c.txManager != nil {
+		return c.txManager, nil
+	}
+
+	inMemory, err := c.memoryDriver()
+	if err != nil {
+		return nil, err
+	}
+	if inMemory {
+		repo, err := c.MemoryCompanyRepo()
+		if err != nil {
+			return nil, err
+		}
+
+		c.txManager = repo
+
+		return c.txManager, nil
+	}
+
+	conn, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := c.conf.Database.Transaction
+	c.txManager, err = db.NewTxManager(conn, db.TxConfig{
+		Isolation:   conf.Isolation,
+		MaxAttempts: conf.MaxAttempts,
+		RetryDelay:  conf.RetryDelay,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new tx manager: %w", err)
+	}
+
+	return c.txManager, nil
+}
+
+// CompanyRepo returns the company repository of the configured database driver,
+// behind the cache if it is enabled.
 func (c *Container) CompanyRepo() (dcompany.Repository, error) {
 	if c.companyRepo != nil {
 		return c.companyRepo, nil
 	}
 
-	conn, err := c.ConnPool()
+	if c.conf.Cache.Enabled {
+		repo, err := c.CompanyCache()
+		if err != nil {
+			return nil, err
+		}
+
+		c.companyRepo = repo
+
+		return c.companyRepo, nil
+	}
+
+	repo, err := c.storageCompanyRepo()
 	if err != nil {
 		return nil, err
 	}
 
-	c.companyRepo = db.NewCompanyPostgresRepository(conn)
+	c.companyRepo = repo
 
 	return c.companyRepo, nil
 }
+
+// storageCompanyRepo returns the company repository of the configured database driver.
+func (c *Container) storageCompanyRepo() (dcompany.Repository, error) {
+	inMemory, err := c.memoryDriver()
+	if err != nil {
+		return nil, err
+	}
+	if inMemory {
+		return c.MemoryCompanyRepo()
+	}
+
+	conn, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	replicas, err := c.ReplicaSet()
+	if err != nil {
+		return nil, err
+	}
+
+	// the relay runs for either of them
+	events := c.conf.Outbox.Enabled || c.conf.Webhooks.Enabled
+
+	return db.NewCompanyPostgresRepository(conn, replicas, events), nil
+}
+
+// CompanyCache returns the caching decorator of the company repository.
+// Invalidations are broadcast through Postgres, so the memory driver keeps them local.
+func (c *Container) CompanyCache() (*cache.CompanyRepository, error) {
+	if c.companyCache != nil {
+		return c.companyCache, nil
+	}
+
+	repo, err := c.storageCompanyRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := c.conf.Cache
+
+	var backend cache.Backend
+	switch conf.Backend {
+	case "", "lru":
+		backend = cache.NewLRU(conf.Size)
+	case "redis":
+		backend = cache.NewRedis(cache.RedisConfig(conf.Redis))
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", conf.Backend)
+	}
+
+	var broadcaster cache.Broadcaster
+	inMemory, err := c.memoryDriver()
+	if err != nil {
+		return nil, err
+	}
+	if conf.Channel != "" && !inMemory {
+		conn, err := c.ConnPool()
+		if err != nil {
+			return nil, err
+		}
+		broadcaster = db.NewCacheBroadcaster(conn, conf.Channel)
+	}
+
+	c.companyCache = cache.NewCompanyRepository(repo, backend, broadcaster, c.Logger().WithName("cache"), cache.Config{
+		TTL:       conf.TTL,
+		KeyPrefix: conf.KeyPrefix,
+	})
+
+	return c.companyCache, nil
+}
+
+// DatabaseRequired reports whether the enabled features use Postgres.
+// With the memory driver only API keys and idempotency keys are kept there,
+// the outbox and webhooks are rejected as company events are not recorded.
+func (c *Container) DatabaseRequired() (bool, error) {
+	inMemory, err := c.memoryDriver()
+	if err != nil {
+		return false, err
+	}
+	if !inMemory {
+		return true, nil
+	}
+
+	if c.conf.Outbox.Enabled || c.conf.Webhooks.Enabled {
+		return false, errors.New("outbox and webhooks need the postgres driver, the memory one records no company events")
+	}
+
+	return c.conf.APIKeys.Enabled || c.conf.Idempotency.Enabled, nil
+}
+
+// memoryDriver reports whether companies are kept in memory.
+func (c *Container) memoryDriver() (bool, error) {
+	switch c.conf.Database.Driver {
+	case "", "postgres":
+		return false, nil
+	case "memory":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown database driver %q", c.conf.Database.Driver)
+	}
+}
+
+// MemoryCompanyRepo returns the in-memory company repository
+// seeded from the fixtures file if one is configured.
+func (c *Container) MemoryCompanyRepo() (*memory.CompanyRepository, error) {
+	if c.memoryRepo != nil {
+		return c.memoryRepo, nil
+	}
+
+	repo := memory.NewCompanyRepository()
+
+	if path := c.conf.Database.Fixtures; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open fixtures: %w", err)
+		}
+		defer f.Close()
+
+		if err := repo.LoadFixtures(context.Background(), f); err != nil {
+			return nil, fmt.Errorf("load fixtures %s: %w", path, err)
+		}
+	}
+
+	c.memoryRepo = repo
+
+	return c.memoryRepo, nil
+}
+
+func (c *Container) GeoClient() *ipapico.Client {
+	if c.geoClient != nil {
+		return c.geoClient
+	}
+
+	c.geoClient = ipapico.New(ipapico.Config{
+		BaseURL:  c.conf.Geo.BaseURL,
+		Timeout:  c.conf.Geo.Timeout,
+		CacheTTL: c.conf.Geo.CacheTTL,
+	})
+
+	return c.geoClient
+}
+
+func (c *Container) TokenVerifier() (*jwt.Verifier, error) {
+	if c.tokenVerifier != nil {
+		return c.tokenVerifier, nil
+	}
+
+	conf := c.conf.Auth
+	keys := &jwt.KeySet{}
+
+	if conf.HMACSecretFile != "" {
+		if err := keys.LoadHMACFile("", conf.HMACSecretFile); err != nil {
+			return nil, fmt.Errorf("load hmac secret: %w", err)
+		}
+	}
+	for _, path := range conf.PublicKeyFiles {
+		if err := keys.LoadPublicKeyFile("", path); err != nil {
+			return nil, fmt.Errorf("load public key %s: %w", path, err)
+		}
+	}
+	if conf.JWKSFile != "" {
+		if err := keys.LoadJWKSFile(conf.JWKSFile); err != nil {
+			return nil, fmt.Errorf("load jwks: %w", err)
+		}
+	}
+	if keys.Len() == 0 {
+		return nil, errors.New("no token verification keys configured")
+	}
+
+	c.tokenVerifier = jwt.NewVerifier(keys, jwt.Config{
+		Issuer:   conf.Issuer,
+		Audience: conf.Audience,
+		Leeway:   conf.Leeway,
+	})
+
+	return c.tokenVerifier, nil
+}
+
+func (c *Container) APIKeyService() (apikey.Service, error) {
+	if c.apiKeyService != nil {
+		return c.apiKeyService, nil
+	}
+
+	repo, err := c.APIKeyRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	c.apiKeyService = apikey.NewService(repo, c.conf.APIKeys.BootstrapKey)
+
+	return c.apiKeyService, nil
+}
+
+func (c *Container) APIKeyRepo() (dapikey.Repository, error) {
+	if c.apiKeyRepo != nil {
+		return c.apiKeyRepo, nil
+	}
+
+	conn, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	c.apiKeyRepo = db.NewAPIKeyPostgresRepository(conn)
+
+	return c.apiKeyRepo, nil
+}
+
+func (c *Container) OutboxRelay() (*outbox.Relay, error) {
+	if c.outboxRelay != nil {
+		return c.outboxRelay, nil
+	}
+
+	conn, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := c.conf.Outbox
+	logger := c.Logger().WithName("outbox")
+
+	var publishers []outbox.Publisher
+	if conf.Enabled {
+		publisher, err := c.outboxPublisher(logger)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, publisher)
+	}
+	if c.conf.Webhooks.Enabled {
+		repo, err := c.WebhookRepo()
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, iwebhook.NewDispatcher(repo))
+	}
+
+	c.outboxRelay = outbox.NewRelay(conn, publishers, logger, outbox.Config{
+		Interval:  conf.Interval,
+		BatchSize: conf.BatchSize,
+		Retention: conf.Retention,
+	})
+
+	return c.outboxRelay, nil
+}
+
+func (c *Container) outboxPublisher(logger log.Interface) (outbox.Publisher, error) {
+	conf := c.conf.Outbox
+
+	switch conf.Publisher {
+	case "", "log":
+		return outbox.LogPublisher{Logger: logger}, nil
+	case "http":
+		if conf.URL == "" {
+			return nil, errors.New("outbox http publisher requires url")
+		}
+		return outbox.NewHTTPPublisher(conf.URL, conf.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", conf.Publisher)
+	}
+}
+
+func (c *Container) WebhookService() (webhook.Service, error) {
+	if c.webhookService != nil {
+		return c.webhookService, nil
+	}
+
+	repo, err := c.WebhookRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	c.webhookService = webhook.NewService(repo)
+
+	return c.webhookService, nil
+}
+
+func (c *Container) WebhookRepo() (dwebhook.Repository, error) {
+	if c.webhookRepo != nil {
+		return c.webhookRepo, nil
+	}
+
+	conn, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	c.webhookRepo = db.NewWebhookPostgresRepository(conn)
+
+	return c.webhookRepo, nil
+}
+
+func (c *Container) WebhookWorker() (*iwebhook.Worker, error) {
+	if c.webhookWorker != nil {
+		return c.webhookWorker, nil
+	}
+
+	repo, err := c.WebhookRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := c.conf.Webhooks
+	c.webhookWorker = iwebhook.NewWorker(repo, c.Logger().WithName("webhook"), iwebhook.Config{
+		Interval:     conf.Interval,
+		BatchSize:    conf.BatchSize,
+		Timeout:      conf.Timeout,
+		MaxAttempts:  conf.MaxAttempts,
+		BackoffBase:  conf.BackoffBase,
+		BackoffMax:   conf.BackoffMax,
+		DisableAfter: conf.DisableAfter,
+	})
+
+	return c.webhookWorker, nil
+}
+
+func (c *Container) Purger() (*company.Purger, error) {
+	if c.purger != nil {
+		return c.purger, nil
+	}
+
+	repo, err := c.CompanyRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := c.conf.Purge
+	purger, err := company.NewPurger(repo, c.Logger().WithName("purge"), company.PurgerConfig{
+		Retention: conf.Retention,
+		Interval:  conf.Interval,
+		BatchSize: conf.BatchSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new purger: %w", err)
+	}
+	c.purger = purger
+
+	return c.purger, nil
+}
+
+func (c *Container) IdempotencyStore() (*db.IdempotencyPostgresStore, error) {
+	if c.idempotencyStore != nil {
+		return c.idempotencyStore, nil
+	}
+
+	conn, err := c.ConnPool()
+	if err != nil {
+		return nil, err
+	}
+
+	c.idempotencyStore = db.NewIdempotencyPostgresStore(conn, c.conf.Idempotency.TTL)
+
+	return c.idempotencyStore, nil
+}
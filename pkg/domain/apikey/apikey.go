@@ -0,0 +1,65 @@
+package apikey
+
+import (
+	"errors"
+	"time"
+)
+
+// Scopes granted to API keys.
+const (
+	ScopeCompaniesRead  = "companies:read"
+	ScopeCompaniesWrite = "companies:write"
+	// ScopeCompaniesAdmin allows reading and restoring deleted companies.
+	ScopeCompaniesAdmin = "companies:admin"
+	ScopeAPIKeysAdmin   = "apikeys:admin"
+	ScopeWebhooksAdmin  = "webhooks:admin"
+)
+
+// Package errors.
+var (
+	ErrNotFound     = errors.New("api key not found")
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrInvalidName  = errors.New("invalid name")
+	ErrInvalidScope = errors.New("unknown scope")
+)
+
+// ValidScope reports whether the scope is known.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeCompaniesRead, ScopeCompaniesWrite, ScopeCompaniesAdmin, ScopeAPIKeysAdmin, ScopeWebhooksAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// APIKey is a long-lived machine credential.
+// The plaintext key is never stored, only its hash.
+type APIKey struct {
+	ID   int
+	Name string
+	// Prefix is the public part of the key used for lookups.
+	Prefix     string
+	Hash       []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// Active reports whether the key is neither revoked nor expired at the time.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// HasScope reports whether the key grants the scope.
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,17 @@
+package apikey
+
+import "context"
+
+//go:generate mockgen -destination=./mocks/apikey_repository.go -package=apikey . Repository
+
+type Repository interface {
+	// Add stores a new key and sets its ID and CreatedAt.
+	Add(ctx context.Context, key *APIKey) error
+	// GetByPrefix returns ErrNotFound if there is no such key.
+	GetByPrefix(ctx context.Context, prefix string) (key APIKey, err error)
+	List(ctx context.Context) (list []APIKey, err error)
+	// Revoke returns ErrNotFound if there is no such active key.
+	Revoke(ctx context.Context, id int) error
+	// Touch records the key usage.
+	Touch(ctx context.Context, id int) error
+}
@@ -0,0 +1,117 @@
+package company
+
+import (
+	"context"
+	"time"
+)
+
+// Audited operations.
+const (
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpPatch   = "patch"
+	OpImport  = "import"
+	OpDelete  = "delete"
+	OpRestore = "restore"
+	// OpPurge records the removal of a soft-deleted company for good.
+	OpPurge = "purge"
+)
+
+// Actor identifies who makes a change and within which request.
+type Actor struct {
+	ID        string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the actor recorded in the audit trail.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, the zero Actor if there is none.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// FieldChange is a change of a single company field.
+// Before is nil for created and After for deleted companies.
+type FieldChange struct {
+	Field  string
+	Before *string
+	After  *string
+}
+
+// AuditRecord is an immutable record of a company change.
+type AuditRecord struct {
+	ID        int64
+	CompanyID int
+	Actor     string
+	RequestID string
+	Operation string
+	Changes   []FieldChange
+	CreatedAt time.Time
+}
+
+// auditFields lists the fields compared by Diff.
+var auditFields = []SortField{SortByCode, SortByName, SortByCountry, SortByWebsite, SortByPhone}
+
+// Diff returns the changed fields, before is nil for created and after for deleted companies.
+// Empty fields of a created or deleted company are left out.
+func Diff(before, after *Company) []FieldChange {
+	value := func(c *Company, f SortField) *string {
+		if c == nil {
+			return nil
+		}
+		v := f.Value(*c)
+		return &v
+	}
+
+	var changes []FieldChange
+	for _, f := range auditFields {
+		b, a := value(before, f), value(after, f)
+
+		switch {
+		case b == nil && a != nil && *a == "",
+			a == nil && b != nil && *b == "",
+			b != nil && a != nil && *b == *a:
+			continue
+		}
+
+		changes = append(changes, FieldChange{Field: string(f), Before: b, After: a})
+	}
+
+	return changes
+}
+
+// HistoryOptions filters the audit trail of a company.
+type HistoryOptions struct {
+	Actor string
+	// Since and Until bound the record time, both are optional and inclusive.
+	Since *time.Time
+	Until *time.Time
+	Limit int
+	// Cursor is the ID of the last record of the previous page.
+	Cursor int64
+}
+
+// PageSize returns the limit clamped to the allowed range.
+func (o HistoryOptions) PageSize() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultPageSize
+	case o.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return o.Limit
+	}
+}
+
+// HistoryPage lists audit records newest first.
+// Next is the cursor of the following page, zero on the last one.
+type HistoryPage struct {
+	Items []AuditRecord
+	Next  int64
+}
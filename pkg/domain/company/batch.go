@@ -0,0 +1,36 @@
+package company
+
+// Outcomes of a batch operation.
+const (
+	BatchApplied = "applied"
+	BatchFailed  = "failed"
+	// BatchAborted marks operations of an atomic batch that were rolled back
+	// or not run because another operation failed.
+	BatchAborted = "aborted"
+)
+
+// Operation is a single write of a batch, Op is one of OpCreate,
+// OpUpdate and OpDelete. Company holds the stored values for create
+// and update, ID and Version select the company for update and delete,
+// a zero Version skips the version check.
+type Operation struct {
+	Op      string
+	ID      int
+	Version int
+	Company Company
+}
+
+// OperationResult is the outcome of a single operation.
+// Company is set for applied creates and updates and Err for failed operations.
+type OperationResult struct {
+	Status  string
+	Company Company
+	Err     error
+}
+
+// BatchReport lists the outcome of every operation in the batch order.
+type BatchReport struct {
+	Results []OperationResult
+	// Committed is false if an atomic batch was rolled back.
+	Committed bool
+}
@@ -1,5 +1,7 @@
 package company
 
+import "time"
+
 type Company struct {
 	ID      int
 	Code    string
@@ -7,7 +9,109 @@ type Company struct {
 	Country string
 	Website string
 	Phone   string
+	// Version is incremented by the storage on every update.
+	Version int
+	// DeletedAt is set for soft-deleted companies,
+	// DeletedBy holds the actor that deleted it.
+	DeletedAt *time.Time
+	DeletedBy string
+}
+
+// Deleted reports whether the company is soft-deleted.
+func (c Company) Deleted() bool {
+	return c.DeletedAt != nil
 }
 
+// GetOptions controls a single company lookup.
+type GetOptions struct {
+	// IncludeDeleted returns soft-deleted companies too.
+	IncludeDeleted bool
+}
+
+// Page size limits for company listings.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ListOptions holds filters and pagination applied to a company listing.
+// Empty fields are ignored.
 type ListOptions struct {
+	// Name matches companies whose name contains the value, case-insensitive.
+	Name string
+	// Code, Country, Website and Phone match exactly.
+	Code    string
+	Country string
+	Website string
+	Phone   string
+	// IncludeDeleted lists soft-deleted companies too.
+	IncludeDeleted bool
+
+	// Sort is the requested ordering, ties are always broken by id.
+	Sort []Sort
+
+	// Limit is the page size. Zero means DefaultPageSize,
+	// values above MaxPageSize are clamped.
+	Limit int
+	// Cursor continues the listing from a page boundary
+	// returned in a previous Page. Nil requests the first page.
+	Cursor *Cursor
+}
+
+// PageSize returns the effective page size.
+func (o ListOptions) PageSize() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultPageSize
+	case o.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return o.Limit
+	}
+}
+
+// Backward reports whether the listing walks towards the previous page.
+func (o ListOptions) Backward() bool {
+	return o.Cursor != nil && o.Cursor.Backward
+}
+
+// Page is a single page of a company listing.
+type Page struct {
+	Items []Company
+	// Next and Prev are nil when there is no page in that direction.
+	Next *Cursor
+	Prev *Cursor
+}
+
+// Paginate builds a page from rows fetched in the listing direction,
+// that is in Order() or in the reverse order when walking backward.
+// Repositories should fetch up to PageSize()+1 rows, so that the extra
+// row tells whether there is a page beyond the current one.
+func (o ListOptions) Paginate(rows []Company) Page {
+	limit := o.PageSize()
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	backward := o.Backward()
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page := Page{Items: rows}
+	if len(rows) == 0 {
+		return page
+	}
+
+	if more || backward {
+		page.Next = o.cursorAt(rows[len(rows)-1], false)
+	}
+	if (backward && more) || (!backward && o.Cursor != nil) {
+		page.Prev = o.cursorAt(rows[0], true)
+	}
+
+	return page
 }
@@ -0,0 +1,184 @@
+package company_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func rows(ids ...int) []company.Company {
+	res := make([]company.Company, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, company.Company{ID: id})
+	}
+	return res
+}
+
+func ids(list []company.Company) []int {
+	res := make([]int, 0, len(list))
+	for _, c := range list {
+		res = append(res, c.ID)
+	}
+	return res
+}
+
+func TestListOptionsPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     company.ListOptions
+		rows     []company.Company
+		wantIDs  []int
+		wantNext *company.Cursor
+		wantPrev *company.Cursor
+	}{
+		{
+			name:    "single first page",
+			opts:    company.ListOptions{Limit: 3},
+			rows:    rows(1, 2),
+			wantIDs: []int{1, 2},
+		},
+		{
+			name:     "first page with more rows",
+			opts:     company.ListOptions{Limit: 2},
+			rows:     rows(1, 2, 3),
+			wantIDs:  []int{1, 2},
+			wantNext: &company.Cursor{ID: 2},
+		},
+		{
+			name:     "forward from cursor",
+			opts:     company.ListOptions{Limit: 2, Cursor: &company.Cursor{ID: 2}},
+			rows:     rows(3, 4),
+			wantIDs:  []int{3, 4},
+			wantPrev: &company.Cursor{ID: 3, Backward: true},
+		},
+		{
+			name:     "backward with more rows",
+			opts:     company.ListOptions{Limit: 2, Cursor: &company.Cursor{ID: 5, Backward: true}},
+			rows:     rows(4, 3, 2),
+			wantIDs:  []int{3, 4},
+			wantNext: &company.Cursor{ID: 4},
+			wantPrev: &company.Cursor{ID: 3, Backward: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := tt.opts.Paginate(tt.rows)
+			if got := ids(page.Items); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Fatalf(`expected ids %v, got %v`, tt.wantIDs, got)
+			}
+			if !reflect.DeepEqual(page.Next, tt.wantNext) {
+				t.Fatalf(`expected next %+v, got %+v`, tt.wantNext, page.Next)
+			}
+			if !reflect.DeepEqual(page.Prev, tt.wantPrev) {
+				t.Fatalf(`expected prev %+v, got %+v`, tt.wantPrev, page.Prev)
+			}
+		})
+	}
+}
+
+func TestCursorEncodeDecode(t *testing.T) {
+	want := company.Cursor{Sort: "-name", Keys: []string{"Acme"}, ID: 42, Backward: true}
+
+	got, err := company.DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Fatalf(`expected cursor %+v, got %+v`, want, *got)
+	}
+
+	if _, err := company.DecodeCursor("not a cursor"); !errors.Is(err, company.ErrInvalidCursor) {
+		t.Fatalf(`expected error %v, got %v`, company.ErrInvalidCursor, err)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	got, err := company.ParseSort("country,-name")
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+
+	want := []company.Sort{
+		{Field: company.SortByCountry},
+		{Field: company.SortByName, Desc: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`expected sort %+v, got %+v`, want, got)
+	}
+	if s := company.FormatSort(got); s != "country,-name" {
+		t.Fatalf(`expected format %q, got %q`, "country,-name", s)
+	}
+
+	for _, s := range []string{"unknown", "name,-name", "-", "name,"} {
+		if _, err := company.ParseSort(s); !errors.Is(err, company.ErrInvalidSort) {
+			t.Fatalf(`expected error %v for %q, got %v`, company.ErrInvalidSort, s, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := company.Company{ID: 1, Name: "Acme", Country: "US", Phone: "+15550100"}
+	after := before
+	after.Phone = "+15550199"
+
+	changes := company.Diff(&before, &after)
+	if len(changes) != 1 || changes[0].Field != "phone" ||
+		*changes[0].Before != "+15550100" || *changes[0].After != "+15550199" {
+		t.Fatalf(`unexpected update diff %+v`, changes)
+	}
+
+	// empty fields of a created company are left out
+	changes = company.Diff(nil, &before)
+	fields := make([]string, 0, len(changes))
+	for _, c := range changes {
+		if c.Before != nil {
+			t.Fatalf(`expected nil before of created company, got %q`, *c.Before)
+		}
+		fields = append(fields, c.Field)
+	}
+	if want := []string{"name", "country", "phone"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf(`expected fields %v, got %v`, want, fields)
+	}
+
+	if changes := company.Diff(&before, &before); len(changes) != 0 {
+		t.Fatalf(`expected no changes, got %+v`, changes)
+	}
+}
+
+func TestSearchOptionsPaginate(t *testing.T) {
+	opts := company.SearchOptions{Limit: 2}
+	hits := []company.SearchHit{
+		{Company: company.Company{ID: 3}, Rank: 0.7},
+		{Company: company.Company{ID: 1}, Rank: 0.1 + 0.2},
+		{Company: company.Company{ID: 2}, Rank: 0.3},
+	}
+
+	page := opts.Paginate(hits)
+	if len(page.Items) != 2 || page.Next == nil {
+		t.Fatalf(`expected 2 hits and a next page, got %+v`, page)
+	}
+
+	decoded, err := company.DecodeCursor(page.Next.Encode())
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	rank, id, err := company.SearchOptions{Cursor: decoded}.CheckCursor()
+	if err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if rank != 0.1+0.2 || id != 1 {
+		t.Fatalf(`expected the cursor at rank %v and id 1, got %v and %d`, 0.1+0.2, rank, id)
+	}
+
+	if page := opts.Paginate(hits[:2]); page.Next != nil {
+		t.Fatalf(`expected no next page, got %+v`, page.Next)
+	}
+
+	listCursor := &company.Cursor{Keys: []string{"Acme"}, ID: 1}
+	if _, _, err := (company.SearchOptions{Cursor: listCursor}).CheckCursor(); !errors.Is(err, company.ErrInvalidCursor) {
+		t.Fatalf(`expected invalid cursor error, got %v`, err)
+	}
+}
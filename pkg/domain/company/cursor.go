@@ -0,0 +1,77 @@
+package company
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+// or does not match the listing it is used with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a keyset pagination position.
+// It points at the boundary row of a page by its sort key and id.
+type Cursor struct {
+	// Sort is the sort specification the cursor was issued for.
+	Sort string `json:"s,omitempty"`
+	// Keys holds values of the sort fields preceding id.
+	Keys []string `json:"k,omitempty"`
+	ID   int      `json:"id"`
+	// Backward is set on cursors pointing at the previous page.
+	Backward bool `json:"b,omitempty"`
+}
+
+func (o ListOptions) cursorAt(c Company, backward bool) *Cursor {
+	order := o.Order()
+
+	var keys []string
+	for _, s := range order[:len(order)-1] {
+		keys = append(keys, s.Field.Value(c))
+	}
+
+	return &Cursor{
+		Sort:     FormatSort(o.Sort),
+		Keys:     keys,
+		ID:       c.ID,
+		Backward: backward,
+	}
+}
+
+// CheckCursor verifies that the cursor was issued for the same ordering.
+func (o ListOptions) CheckCursor() error {
+	if o.Cursor == nil {
+		return nil
+	}
+
+	if o.Cursor.Sort != FormatSort(o.Sort) || len(o.Cursor.Keys) != len(o.Order())-1 {
+		return invalidCursor()
+	}
+
+	return nil
+}
+
+func invalidCursor() error {
+	return &ValidationError{Detail: "cursor is malformed or issued for another ordering", Err: ErrInvalidCursor}
+}
+
+// Encode returns an opaque URL-safe representation of the cursor.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c) // nolint:errchkjson // cursor fields are always serializable
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalidCursor()
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, invalidCursor()
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,77 @@
+package company
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotFoundError is returned when a company does not exist.
+type NotFoundError struct {
+	ID int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("company %d not found", e.ID)
+}
+
+// ConflictError is returned when a write clashes with the stored data,
+// for example a duplicate company code.
+type ConflictError struct {
+	Detail string
+	Err    error
+}
+
+func (e *ConflictError) Error() string {
+	return "conflict: " + e.Detail
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when the input is not acceptable.
+// Fields lists offending company fields, it is empty
+// when the input is rejected as a whole.
+type ValidationError struct {
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return "validation: " + e.Detail
+	}
+
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+
+	return "validation: " + e.Detail + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// FieldError describes a single field that broke a validation rule.
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// PreconditionFailedError is returned when a conditional write
+// does not match the stored state of the company.
+type PreconditionFailedError struct {
+	Detail string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return "precondition failed: " + e.Detail
+}
@@ -0,0 +1,13 @@
+package company
+
+// Types of company change events.
+const (
+	EventCreated  = "company.created"
+	EventUpdated  = "company.updated"
+	EventDeleted  = "company.deleted"
+	EventRestored = "company.restored"
+)
+
+// EventSchemaVersion is the version of the change event payload layout.
+// It must be bumped on incompatible payload changes.
+const EventSchemaVersion = 1
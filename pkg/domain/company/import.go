@@ -0,0 +1,59 @@
+package company
+
+// Import outcomes of a row.
+const (
+	ImportAccepted  = "accepted"
+	ImportRejected  = "rejected"
+	ImportDuplicate = "duplicate"
+)
+
+// ImportRow is a company read from an import input.
+// Err is set for rows that cannot be imported, e.g. malformed or invalid ones.
+type ImportRow struct {
+	Line    int
+	Company Company
+	Err     error
+}
+
+// ImportSource streams the rows of an import.
+type ImportSource interface {
+	// Next advances to the next row, it returns false
+	// at the end of the input or on a fatal error.
+	Next() bool
+	Row() ImportRow
+	// Err returns the fatal error that stopped the input.
+	Err() error
+}
+
+// ImportResult is the outcome of a single row.
+// ID is set for accepted rows and Err for rejected ones.
+type ImportResult struct {
+	Line   int
+	Status string
+	ID     int
+	Err    error
+}
+
+// ImportReport lists the outcome of every row ordered by line.
+type ImportReport struct {
+	Rows       []ImportResult
+	Accepted   int
+	Rejected   int
+	Duplicates int
+	// Committed is false if an atomic import was rolled back.
+	Committed bool
+}
+
+// Add appends the result and counts it.
+func (r *ImportReport) Add(res ImportResult) {
+	r.Rows = append(r.Rows, res)
+
+	switch res.Status {
+	case ImportAccepted:
+		r.Accepted++
+	case ImportRejected:
+		r.Rejected++
+	case ImportDuplicate:
+		r.Duplicates++
+	}
+}
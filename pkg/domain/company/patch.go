@@ -0,0 +1,33 @@
+package company
+
+// Patch is a partial company update.
+// Nil fields are left unchanged, an empty value clears the field.
+type Patch struct {
+	Code    *string
+	Name    *string
+	Country *string
+	Website *string
+	Phone   *string
+}
+
+// Empty reports whether the patch changes nothing.
+func (p Patch) Empty() bool {
+	return p.Code == nil && p.Name == nil && p.Country == nil && p.Website == nil && p.Phone == nil
+}
+
+// Apply returns a copy of the company with the patch applied.
+func (p Patch) Apply(c Company) Company {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	set(&c.Code, p.Code)
+	set(&c.Name, p.Name)
+	set(&c.Country, p.Country)
+	set(&c.Website, p.Website)
+	set(&c.Phone, p.Phone)
+
+	return c
+}
@@ -1,12 +1,77 @@
 package company
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 //go:generate mockgen -destination=./mocks/company_repository.go -package=company . Repository
 
+// Repository stores companies.
+// Implementations report failures with the error types of this package.
+//
+// Writes are conditional: a non-zero version (Company.Version for Update)
+// must match the stored one, otherwise *PreconditionFailedError is returned.
+// The check and the write are atomic.
+//
+// Every write is recorded in the audit trail of the company
+// on behalf of the actor set in the context with WithActor.
+//
+// Implementations take part in the transaction carried by the context,
+// see transaction.Manager.
+//
+// Deletes are soft: deleted companies are hidden from reads unless
+// requested with IncludeDeleted, cannot be changed until restored
+// and are removed for good by Purge.
 type Repository interface {
-	Add(ctx context.Context, company Company) error
-	List(ctx context.Context, options ListOptions) (list []Company, err error)
+	// Add stores a new company and sets its ID.
+	Add(ctx context.Context, company *Company) error
+	Get(ctx context.Context, id int, options GetOptions) (company Company, err error)
+	List(ctx context.Context, options ListOptions) (page Page, err error)
+	Count(ctx context.Context, options ListOptions) (count int, err error)
+	// Search returns a page of companies matching the query best first,
+	// it is up to the implementation how close a match must be.
+	// Deleted companies are never found.
+	Search(ctx context.Context, options SearchOptions) (page SearchPage, err error)
+	// Update overwrites the company with the given ID
+	// and refreshes it with the stored values.
 	Update(ctx context.Context, company *Company) error
-	Delete(ctx context.Context, id int) error
+	// Patch changes only the fields set in the patch
+	// and returns the stored company.
+	Patch(ctx context.Context, id, version int, patch Patch) (company Company, err error)
+	// Delete marks the company deleted by the actor of the context.
+	Delete(ctx context.Context, id, version int) error
+	// Restore undeletes the company and returns it.
+	// Restoring a company that is not deleted is a *ConflictError.
+	Restore(ctx context.Context, id, version int) (company Company, err error)
+	// Purge removes up to limit companies deleted before the time
+	// and returns the number of removed companies.
+	Purge(ctx context.Context, deletedBefore time.Time, limit int) (count int, err error)
+	// Export streams all companies matching the filters of the options
+	// in their order to fn, limit and cursor are ignored.
+	// It stops at the first error returned by fn.
+	Export(ctx context.Context, options ListOptions, fn func(Company) error) error
+	// Import stores the valid rows of the source as new companies in one go,
+	// rows with a code already taken, by a stored company or an earlier row,
+	// are reported as duplicates. Rows with Err set are reported as rejected.
+	// An atomic import stores nothing unless every row is accepted.
+	Import(ctx context.Context, source ImportSource, atomic bool) (report ImportReport, err error)
+	// History returns the audit trail of the company newest first,
+	// it is kept after the company is deleted.
+	History(ctx context.Context, id int, options HistoryOptions) (page HistoryPage, err error)
+}
+
+type primaryReadKey struct{}
+
+// WithPrimaryRead returns a context asking repositories that spread reads
+// over replicas to read from the primary, for results kept beyond
+// the request, e.g. in a cache, which must not come from a lagging replica.
+func WithPrimaryRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadKey{}, true)
+}
+
+// PrimaryRead reports whether the context was marked by WithPrimaryRead.
+func PrimaryRead(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryReadKey{}).(bool)
+	return primary
 }
@@ -0,0 +1,86 @@
+package company
+
+import "strconv"
+
+// searchSort is the Cursor.Sort of search cursors, their single key is the rank.
+const searchSort = "rank"
+
+// SearchOptions holds a full-text search over companies.
+type SearchOptions struct {
+	// Query is matched against names and codes, partially
+	// and tolerating misspellings. It must not be empty.
+	Query string
+	// Country limits the results to the country when set.
+	Country string
+	// Limit is the page size, see ListOptions.Limit.
+	Limit int
+	// Cursor continues the search after the last hit of a previous page.
+	Cursor *Cursor
+}
+
+// PageSize returns the effective page size.
+func (o SearchOptions) PageSize() int {
+	return ListOptions{Limit: o.Limit}.PageSize()
+}
+
+// CheckCursor verifies that the cursor was issued by a search
+// and returns the rank and the company id it points after.
+func (o SearchOptions) CheckCursor() (rank float64, id int, err error) {
+	if o.Cursor == nil {
+		return 0, 0, nil
+	}
+	if o.Cursor.Sort != searchSort || len(o.Cursor.Keys) != 1 || o.Cursor.Backward {
+		return 0, 0, invalidCursor()
+	}
+
+	rank, err = strconv.ParseFloat(o.Cursor.Keys[0], 64)
+	if err != nil {
+		return 0, 0, invalidCursor()
+	}
+
+	return rank, o.Cursor.ID, nil
+}
+
+// SearchHit is a company matching a search.
+// Hits are ordered by Rank, higher first, and then by company id.
+type SearchHit struct {
+	Company Company
+	Rank    float64
+	// Highlight is the company name with the matched words
+	// wrapped in HighlightStart and HighlightStop.
+	Highlight string
+}
+
+// Highlight markers of the matched words.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// SearchPage is a single page of search hits.
+// Next is nil on the last page.
+type SearchPage struct {
+	Items []SearchHit
+	Next  *Cursor
+}
+
+// Paginate builds a page from up to PageSize()+1 hits,
+// the extra hit tells whether there is a next page.
+func (o SearchOptions) Paginate(hits []SearchHit) SearchPage {
+	limit := o.PageSize()
+	if len(hits) <= limit {
+		return SearchPage{Items: hits}
+	}
+
+	hits = hits[:limit]
+	last := hits[len(hits)-1]
+
+	return SearchPage{
+		Items: hits,
+		Next: &Cursor{
+			Sort: searchSort,
+			Keys: []string{strconv.FormatFloat(last.Rank, 'g', -1, 64)},
+			ID:   last.Company.ID,
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package company
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSort is returned when a sort specification cannot be parsed.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortField is a company field that listings can be ordered by.
+type SortField string
+
+// Sortable fields.
+const (
+	SortByID      SortField = "id"
+	SortByCode    SortField = "code"
+	SortByName    SortField = "name"
+	SortByCountry SortField = "country"
+	SortByWebsite SortField = "website"
+	SortByPhone   SortField = "phone"
+)
+
+// Value returns the value of the field in the company.
+// ID is not a string field and yields an empty value, it is compared by Company.ID.
+func (f SortField) Value(c Company) string {
+	switch f {
+	case SortByCode:
+		return c.Code
+	case SortByName:
+		return c.Name
+	case SortByCountry:
+		return c.Country
+	case SortByWebsite:
+		return c.Website
+	case SortByPhone:
+		return c.Phone
+	default:
+		return ""
+	}
+}
+
+func (f SortField) valid() bool {
+	switch f {
+	case SortByID, SortByCode, SortByName, SortByCountry, SortByWebsite, SortByPhone:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sort orders a listing by a single field.
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ParseSort parses a comma separated list of fields,
+// a field prefixed with "-" is sorted in descending order.
+// For example "country,-name".
+func ParseSort(s string) ([]Sort, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var (
+		res  []Sort
+		seen = map[SortField]bool{}
+	)
+	for _, part := range strings.Split(s, ",") {
+		var srt Sort
+		if strings.HasPrefix(part, "-") {
+			srt.Desc = true
+			part = part[1:]
+		}
+		srt.Field = SortField(part)
+
+		if !srt.Field.valid() || seen[srt.Field] {
+			return nil, &ValidationError{Detail: fmt.Sprintf("cannot sort by %q", part), Err: ErrInvalidSort}
+		}
+		seen[srt.Field] = true
+
+		res = append(res, srt)
+	}
+
+	return res, nil
+}
+
+// FormatSort is the reverse of ParseSort.
+func FormatSort(sorts []Sort) string {
+	parts := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		if s.Desc {
+			parts = append(parts, "-"+string(s.Field))
+		} else {
+			parts = append(parts, string(s.Field))
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Order returns the effective ordering of the listing.
+// It is deterministic: ties are broken by id, which is always the last key.
+func (o ListOptions) Order() []Sort {
+	var res []Sort
+	for _, s := range o.Sort {
+		res = append(res, s)
+		if s.Field == SortByID {
+			return res
+		}
+	}
+
+	return append(res, Sort{Field: SortByID})
+}
@@ -0,0 +1,34 @@
+// Package transaction defines the unit of work shared by repositories.
+package transaction
+
+import "context"
+
+//go:generate mockgen -destination=./mocks/manager.go -package=transaction . Manager
+
+// Manager runs functions atomically. The transaction is carried
+// by the context passed to the function, repositories called with
+// that context take part in it.
+type Manager interface {
+	// Do runs fn in a transaction, committed if fn returns nil
+	// and rolled back otherwise. Called with a context already
+	// carrying a transaction, fn runs in a savepoint of it, so that
+	// a failure of the nested call can be handled by the outer one.
+	// Top level transactions failing on serialization or deadlock
+	// are retried, so fn must be safe to run again.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type activeKey struct{}
+
+// WithActive marks the context as carrying a transaction,
+// managers mark the contexts they pass to fn.
+func WithActive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, activeKey{}, true)
+}
+
+// Active reports whether the context carries a transaction,
+// whose writes are not visible to others until it commits.
+func Active(ctx context.Context) bool {
+	active, _ := ctx.Value(activeKey{}).(bool)
+	return active
+}
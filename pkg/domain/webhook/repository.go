@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+//go:generate mockgen -destination=./mocks/webhook_repository.go -package=webhook . Repository
+
+type Repository interface {
+	// Add stores a new webhook and sets its ID and timestamps.
+	Add(ctx context.Context, w *Webhook) error
+	// Get returns ErrNotFound if there is no such webhook.
+	Get(ctx context.Context, id int) (w Webhook, err error)
+	List(ctx context.Context) (list []Webhook, err error)
+	// Update overwrites URL, events, secret and the active flag.
+	// Failures are reset when an inactive webhook is activated.
+	Update(ctx context.Context, w *Webhook) error
+	// Delete removes the webhook along with its jobs and delivery log.
+	Delete(ctx context.Context, id int) error
+	// Deliveries returns the latest delivery attempts first.
+	Deliveries(ctx context.Context, webhookID, limit int) (list []Delivery, err error)
+
+	// Enqueue schedules the event delivery to every active webhook subscribed to it.
+	// Enqueueing the same event again is a no-op.
+	Enqueue(ctx context.Context, eventID int64, eventType string, payload []byte) error
+	// Claim leases up to limit due jobs of active webhooks, a job is due
+	// again once the lease expires, so that a crashed worker does not lose it.
+	Claim(ctx context.Context, limit int, lease time.Duration) (jobs []Job, err error)
+	// Complete records a successful delivery and finishes the job.
+	Complete(ctx context.Context, job Job, d Delivery) error
+	// Fail records a failed delivery. The job is retried at retryAt or finished
+	// if retryAt is nil. The webhook is disabled after disableAfter consecutive failures.
+	Fail(ctx context.Context, job Job, d Delivery, retryAt *time.Time, disableAfter int) error
+}
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// Package errors.
+var (
+	ErrNotFound      = errors.New("webhook not found")
+	ErrInvalidURL    = errors.New("invalid url")
+	ErrInvalidEvent  = errors.New("unknown event type")
+	ErrInvalidSecret = errors.New("invalid secret")
+)
+
+// ValidEvent reports whether webhooks can subscribe to the event type.
+func ValidEvent(event string) bool {
+	switch event {
+	case company.EventCreated, company.EventUpdated, company.EventDeleted, company.EventRestored:
+		return true
+	default:
+		return false
+	}
+}
+
+// Webhook is a subscription of a target URL to change events.
+type Webhook struct {
+	ID     int
+	URL    string
+	Events []string
+	// Secret signs the deliveries.
+	Secret string
+	Active bool
+	// Failures counts consecutive failed delivery attempts,
+	// the webhook is disabled once it gets over the limit.
+	Failures  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribed reports whether the webhook wants the event type.
+func (w Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Job is a pending delivery of an event to a webhook.
+type Job struct {
+	ID        int64
+	Webhook   Webhook
+	EventID   int64
+	EventType string
+	Payload   []byte
+	// Attempt is the number of the current attempt starting at 1.
+	Attempt int
+}
+
+// Delivery is a record of a single delivery attempt.
+type Delivery struct {
+	ID        int64
+	WebhookID int
+	EventID   int64
+	EventType string
+	Attempt   int
+	// StatusCode is zero if no response was received.
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// Succeeded reports whether the target accepted the delivery.
+func (d Delivery) Succeeded() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode <= 299
+}
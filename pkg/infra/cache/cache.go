@@ -0,0 +1,37 @@
+// Package cache keeps the results of company reads
+// in a local or a shared key-value store.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by backends for keys that are not cached.
+var ErrMiss = errors.New("cache miss")
+
+// Backend is a key-value store with expiring entries.
+type Backend interface {
+	// Get returns the value of the key or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value for the ttl, zero means until evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Broadcaster spreads invalidations across the replicas of the service.
+type Broadcaster interface {
+	// Broadcast sends the message to every listener, including this replica.
+	// Within a transaction carried by the context it is sent on commit.
+	Broadcast(ctx context.Context, msg string) error
+	// Listen passes the received messages to fn until the context
+	// is canceled or the subscription fails.
+	Listen(ctx context.Context, fn func(msg string)) error
+}
+
+// Stats counts the lookups of cached reads.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
@@ -0,0 +1,323 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/transaction"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+const (
+	defaultTTL        = time.Minute
+	defaultKeyPrefix  = "companies:"
+	defaultRetryDelay = 5 * time.Second
+)
+
+// listsOnly is the invalidation message of writes that change no cached company.
+const listsOnly = "0"
+
+type Config struct {
+	// TTL bounds how long an entry is served, defaults to 1m.
+	// It also bounds the staleness left by lost invalidations.
+	TTL time.Duration
+	// KeyPrefix separates the keys of the service in a shared backend,
+	// defaults to "companies:".
+	KeyPrefix string
+	// RetryDelay between attempts to listen for invalidations, defaults to 5s.
+	RetryDelay time.Duration
+}
+
+// CompanyRepository is a read-through cache of company lookups and list pages
+// decorating another repository. Every write invalidates the company it
+// changes along with all list pages, locally and, through the broadcaster,
+// on the other replicas. List pages are keyed by a generation, invalidating
+// them is replacing the generation.
+//
+// Deleted companies and reads within a transaction are never cached,
+// the latter may see writes that are rolled back later. Misses are filled
+// from the primary, see company.WithPrimaryRead, so that a replica lagging
+// behind an invalidation does not put stale data back for the TTL.
+type CompanyRepository struct {
+	company.Repository
+
+	backend     Backend
+	broadcaster Broadcaster
+	logger      log.Interface
+	conf        Config
+
+	hits   uint64
+	misses uint64
+}
+
+// NewCompanyRepository wraps the repository. The broadcaster is optional,
+// without it invalidations stay within this replica.
+func NewCompanyRepository(next company.Repository, backend Backend, broadcaster Broadcaster, logger log.Interface, conf Config) *CompanyRepository {
+	if conf.TTL <= 0 {
+		conf.TTL = defaultTTL
+	}
+	if conf.KeyPrefix == "" {
+		conf.KeyPrefix = defaultKeyPrefix
+	}
+	if conf.RetryDelay <= 0 {
+		conf.RetryDelay = defaultRetryDelay
+	}
+
+	return &CompanyRepository{
+		Repository:  next,
+		backend:     backend,
+		broadcaster: broadcaster,
+		logger:      logger,
+		conf:        conf,
+	}
+}
+
+// Stats returns the hits and misses of cached reads so far.
+func (r *CompanyRepository) Stats() Stats {
+	return Stats{
+		Hits:   atomic.LoadUint64(&r.hits),
+		Misses: atomic.LoadUint64(&r.misses),
+	}
+}
+
+func (r *CompanyRepository) Get(ctx context.Context, id int, opts company.GetOptions) (company.Company, error) {
+	if opts.IncludeDeleted || transaction.Active(ctx) {
+		return r.Repository.Get(ctx, id, opts)
+	}
+
+	var row company.Company
+	key := r.companyKey(id)
+	if r.lookup(ctx, key, &row) {
+		return row, nil
+	}
+
+	row, err := r.Repository.Get(company.WithPrimaryRead(ctx), id, opts)
+	if err != nil {
+		return company.Company{}, err
+	}
+	r.store(ctx, key, row)
+
+	return row, nil
+}
+
+func (r *CompanyRepository) List(ctx context.Context, opts company.ListOptions) (company.Page, error) {
+	if transaction.Active(ctx) {
+		return r.Repository.List(ctx, opts)
+	}
+
+	key, err := r.listKey(ctx, opts)
+	if err != nil {
+		r.logger.Error(err, "cache list key")
+		return r.Repository.List(ctx, opts)
+	}
+
+	var page company.Page
+	if r.lookup(ctx, key, &page) {
+		return page, nil
+	}
+
+	page, err = r.Repository.List(company.WithPrimaryRead(ctx), opts)
+	if err != nil {
+		return company.Page{}, err
+	}
+	r.store(ctx, key, page)
+
+	return page, nil
+}
+
+func (r *CompanyRepository) Add(ctx context.Context, row *company.Company) error {
+	if err := r.Repository.Add(ctx, row); err != nil {
+		return err
+	}
+	r.invalidate(ctx, 0)
+
+	return nil
+}
+
+func (r *CompanyRepository) Update(ctx context.Context, row *company.Company) error {
+	err := r.Repository.Update(ctx, row)
+	r.invalidate(ctx, row.ID)
+
+	return err
+}
+
+func (r *CompanyRepository) Patch(ctx context.Context, id, version int, patch company.Patch) (company.Company, error) {
+	row, err := r.Repository.Patch(ctx, id, version, patch)
+	r.invalidate(ctx, id)
+
+	return row, err
+}
+
+func (r *CompanyRepository) Delete(ctx context.Context, id, version int) error {
+	err := r.Repository.Delete(ctx, id, version)
+	r.invalidate(ctx, id)
+
+	return err
+}
+
+func (r *CompanyRepository) Restore(ctx context.Context, id, version int) (company.Company, error) {
+	row, err := r.Repository.Restore(ctx, id, version)
+	r.invalidate(ctx, id)
+
+	return row, err
+}
+
+// Purge removes deleted companies only, which are not cached,
+// but lists including deleted companies change.
+func (r *CompanyRepository) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	count, err := r.Repository.Purge(ctx, deletedBefore, limit)
+	if count > 0 {
+		r.invalidate(ctx, 0)
+	}
+
+	return count, err
+}
+
+func (r *CompanyRepository) Import(ctx context.Context, source company.ImportSource, atomic bool) (company.ImportReport, error) {
+	report, err := r.Repository.Import(ctx, source, atomic)
+	if report.Committed && report.Accepted > 0 {
+		r.invalidate(ctx, 0)
+	}
+
+	return report, err
+}
+
+// Run applies the invalidations broadcast by the replicas until the context is canceled.
+// Without a broadcaster it returns at once.
+func (r *CompanyRepository) Run(ctx context.Context) {
+	if r.broadcaster == nil {
+		return
+	}
+
+	for {
+		err := r.broadcaster.Listen(ctx, func(msg string) {
+			id, err := strconv.Atoi(msg)
+			if err != nil {
+				r.logger.Error(err, "cache invalidation", "message", msg)
+				return
+			}
+			r.evict(id)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		r.logger.Error(err, "listen for cache invalidations")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.conf.RetryDelay):
+		}
+	}
+}
+
+// invalidate evicts the company, if id is not zero, and all list pages
+// and broadcasts the invalidation. Changes of existing companies invalidate
+// after failures too, the write may have been applied before it failed.
+func (r *CompanyRepository) invalidate(ctx context.Context, id int) {
+	r.evict(id)
+
+	if r.broadcaster == nil {
+		return
+	}
+
+	msg := listsOnly
+	if id != 0 {
+		msg = strconv.Itoa(id)
+	}
+	// within a transaction the message goes out on commit,
+	// evicting whatever was cached before the commit
+	if err := r.broadcaster.Broadcast(ctx, msg); err != nil {
+		r.logger.Error(err, "broadcast cache invalidation", "id", id)
+	}
+}
+
+// evict removes the entries from the backend. It ignores the cancellation
+// of the context, eviction must not fail along with the write triggering it.
+func (r *CompanyRepository) evict(id int) {
+	ctx := context.Background()
+
+	if id != 0 {
+		if err := r.backend.Delete(ctx, r.companyKey(id)); err != nil {
+			r.logger.Error(err, "cache evict", "id", id)
+		}
+	}
+
+	if err := r.backend.Set(ctx, r.generationKey(), []byte(newGeneration()), 0); err != nil {
+		r.logger.Error(err, "cache evict lists")
+	}
+}
+
+// lookup decodes the cached value of the key into v and counts the hit or miss.
+// Backend failures are logged and count as misses.
+func (r *CompanyRepository) lookup(ctx context.Context, key string, v interface{}) bool {
+	raw, err := r.backend.Get(ctx, key)
+	if err == nil {
+		err = json.Unmarshal(raw, v)
+	}
+	if err != nil {
+		if !errors.Is(err, ErrMiss) {
+			r.logger.Error(err, "cache get", "key", key)
+		}
+		atomic.AddUint64(&r.misses, 1)
+		return false
+	}
+
+	atomic.AddUint64(&r.hits, 1)
+	return true
+}
+
+func (r *CompanyRepository) store(ctx context.Context, key string, v interface{}) {
+	raw, err := json.Marshal(v)
+	if err == nil {
+		err = r.backend.Set(ctx, key, raw, r.conf.TTL)
+	}
+	if err != nil {
+		r.logger.Error(err, "cache set", "key", key)
+	}
+}
+
+func (r *CompanyRepository) companyKey(id int) string {
+	return r.conf.KeyPrefix + "id:" + strconv.Itoa(id)
+}
+
+func (r *CompanyRepository) generationKey() string {
+	return r.conf.KeyPrefix + "list:generation"
+}
+
+// listKey returns the key of the page of the current generation,
+// starting a generation if there is none.
+func (r *CompanyRepository) listKey(ctx context.Context, opts company.ListOptions) (string, error) {
+	raw, err := r.backend.Get(ctx, r.generationKey())
+	if errors.Is(err, ErrMiss) {
+		raw = []byte(newGeneration())
+		err = r.backend.Set(ctx, r.generationKey(), raw, 0)
+	}
+	if err != nil {
+		return "", fmt.Errorf("list generation: %w", err)
+	}
+
+	spec, err := json.Marshal(opts)
+	if err != nil {
+		return "", fmt.Errorf("marshal list options: %w", err)
+	}
+	sum := sha256.Sum256(spec)
+
+	return r.conf.KeyPrefix + "list:" + string(raw) + ":" + hex.EncodeToString(sum[:16]), nil
+}
+
+func newGeneration() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
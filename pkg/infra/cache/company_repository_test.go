@@ -0,0 +1,215 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/cache"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/memory"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+// chanBroadcaster delivers broadcast messages to its listener through a channel.
+type chanBroadcaster struct {
+	mu   sync.Mutex
+	sent []string
+	ch   chan string
+}
+
+func (b *chanBroadcaster) Broadcast(_ context.Context, msg string) error {
+	b.mu.Lock()
+	b.sent = append(b.sent, msg)
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *chanBroadcaster) Listen(ctx context.Context, fn func(msg string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-b.ch:
+			fn(msg)
+		}
+	}
+}
+
+func newCachedRepo(t *testing.T, broadcaster cache.Broadcaster) (*cache.CompanyRepository, *memory.CompanyRepository) {
+	t.Helper()
+
+	storage := memory.NewCompanyRepository()
+	err := storage.LoadFixtures(context.Background(), strings.NewReader(`[
+		{"code": "C1", "name": "Beta"},
+		{"code": "C2", "name": "Gamma"}
+	]`))
+	if err != nil {
+		t.Fatalf(`LoadFixtures() error = %v`, err)
+	}
+
+	repo := cache.NewCompanyRepository(storage, cache.NewLRU(0), broadcaster, log.Logger, cache.Config{TTL: time.Minute})
+
+	return repo, storage
+}
+
+func TestCompanyRepositoryReadThrough(t *testing.T) {
+	broadcaster := &chanBroadcaster{}
+	repo, storage := newCachedRepo(t, broadcaster)
+	ctx := context.Background()
+
+	get := func() company.Company {
+		t.Helper()
+		c, err := repo.Get(ctx, 1, company.GetOptions{})
+		if err != nil {
+			t.Fatalf(`Get() error = %v`, err)
+		}
+		return c
+	}
+	list := func() company.Page {
+		t.Helper()
+		page, err := repo.List(ctx, company.ListOptions{})
+		if err != nil {
+			t.Fatalf(`List() error = %v`, err)
+		}
+		return page
+	}
+
+	get()
+	list()
+	// writes bypassing the cache are not seen until invalidated
+	if _, err := storage.Patch(ctx, 1, 0, company.Patch{Name: strPtr("Beta Inc")}); err != nil {
+		t.Fatalf(`Patch() error = %v`, err)
+	}
+	if c := get(); c.Name != "Beta" {
+		t.Fatalf(`Get() = %q, want the cached "Beta"`, c.Name)
+	}
+	if page := list(); len(page.Items) != 2 || page.Items[0].Name != "Beta" {
+		t.Fatalf(`List() = %+v, want the cached page`, page.Items)
+	}
+	if stats := repo.Stats(); stats != (cache.Stats{Hits: 2, Misses: 2}) {
+		t.Fatalf(`Stats() = %+v, want 2 hits and 2 misses`, stats)
+	}
+
+	if err := repo.Delete(ctx, 2, 0); err != nil {
+		t.Fatalf(`Delete() error = %v`, err)
+	}
+	if page := list(); len(page.Items) != 1 || page.Items[0].Name != "Beta Inc" {
+		t.Fatalf(`List() after Delete() = %+v, want Beta Inc only`, page.Items)
+	}
+	if _, err := repo.Get(ctx, 2, company.GetOptions{}); err == nil {
+		t.Fatalf(`Get() of a deleted company error = nil, want *NotFoundError`)
+	}
+
+	if err := repo.Add(ctx, &company.Company{Code: "C3", Name: "Delta"}); err != nil {
+		t.Fatalf(`Add() error = %v`, err)
+	}
+	if page := list(); len(page.Items) != 2 {
+		t.Fatalf(`List() after Add() = %+v, want 2 companies`, page.Items)
+	}
+
+	if want := []string{"2", "0"}; strings.Join(broadcaster.sent, ",") != strings.Join(want, ",") {
+		t.Fatalf(`broadcast = %q, want %q`, broadcaster.sent, want)
+	}
+}
+
+func TestCompanyRepositoryTransaction(t *testing.T) {
+	repo, storage := newCachedRepo(t, nil)
+
+	err := storage.Do(context.Background(), func(ctx context.Context) error {
+		if _, err := repo.Patch(ctx, 1, 0, company.Patch{Name: strPtr("Beta Inc")}); err != nil {
+			return err
+		}
+		// uncommitted data must not be cached
+		_, err := repo.Get(ctx, 1, company.GetOptions{})
+		return err
+	})
+	if err != nil {
+		t.Fatalf(`Do() error = %v`, err)
+	}
+
+	c, err := repo.Get(context.Background(), 1, company.GetOptions{})
+	if err != nil {
+		t.Fatalf(`Get() error = %v`, err)
+	}
+	if c.Name != "Beta Inc" {
+		t.Fatalf(`Get() = %q, want "Beta Inc"`, c.Name)
+	}
+	if stats := repo.Stats(); stats != (cache.Stats{Misses: 1}) {
+		t.Fatalf(`Stats() = %+v, want a single miss`, stats)
+	}
+}
+
+// primaryReads records whether the reads of the wrapped repository ask for the primary.
+type primaryReads struct {
+	company.Repository
+	reads []bool
+}
+
+func (r *primaryReads) Get(ctx context.Context, id int, opts company.GetOptions) (company.Company, error) {
+	r.reads = append(r.reads, company.PrimaryRead(ctx))
+	return r.Repository.Get(ctx, id, opts)
+}
+
+func (r *primaryReads) List(ctx context.Context, opts company.ListOptions) (company.Page, error) {
+	r.reads = append(r.reads, company.PrimaryRead(ctx))
+	return r.Repository.List(ctx, opts)
+}
+
+func TestCompanyRepositoryFillsFromPrimary(t *testing.T) {
+	_, storage := newCachedRepo(t, nil)
+	reads := &primaryReads{Repository: storage}
+	repo := cache.NewCompanyRepository(reads, cache.NewLRU(0), nil, log.Logger, cache.Config{})
+	ctx := context.Background()
+
+	if _, err := repo.Get(ctx, 1, company.GetOptions{}); err != nil {
+		t.Fatalf(`Get() error = %v`, err)
+	}
+	if _, err := repo.List(ctx, company.ListOptions{}); err != nil {
+		t.Fatalf(`List() error = %v`, err)
+	}
+	// reads that are not cached may still go to a replica
+	if _, err := repo.Get(ctx, 1, company.GetOptions{IncludeDeleted: true}); err != nil {
+		t.Fatalf(`Get() error = %v`, err)
+	}
+
+	if want := []bool{true, true, false}; fmt.Sprint(reads.reads) != fmt.Sprint(want) {
+		t.Fatalf(`primary reads = %v, want %v`, reads.reads, want)
+	}
+}
+
+func TestCompanyRepositoryRun(t *testing.T) {
+	broadcaster := &chanBroadcaster{ch: make(chan string)}
+	repo, storage := newCachedRepo(t, broadcaster)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go repo.Run(ctx)
+
+	if _, err := repo.Get(ctx, 1, company.GetOptions{}); err != nil {
+		t.Fatalf(`Get() error = %v`, err)
+	}
+	if _, err := storage.Patch(ctx, 1, 0, company.Patch{Name: strPtr("Beta Inc")}); err != nil {
+		t.Fatalf(`Patch() error = %v`, err)
+	}
+
+	// an invalidation of another replica, the second send waits for the first to be applied
+	broadcaster.ch <- "1"
+	broadcaster.ch <- "0"
+
+	c, err := repo.Get(ctx, 1, company.GetOptions{})
+	if err != nil {
+		t.Fatalf(`Get() error = %v`, err)
+	}
+	if c.Name != "Beta Inc" {
+		t.Fatalf(`Get() after invalidation = %q, want "Beta Inc"`, c.Name)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
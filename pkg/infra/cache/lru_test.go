@@ -0,0 +1,44 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/cache"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(2)
+
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	// a becomes the most recently used, so b is evicted
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatalf(`Get(a) error = %v`, err)
+	}
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrMiss) {
+		t.Fatalf(`Get(b) error = %v, want ErrMiss`, err)
+	}
+	if v, err := c.Get(ctx, "c"); err != nil || string(v) != "3" {
+		t.Fatalf(`Get(c) = %q, %v, want "3"`, v, err)
+	}
+	if c.Len() != 2 {
+		t.Fatalf(`Len() = %d, want 2`, c.Len())
+	}
+
+	_ = c.Delete(ctx, "a", "missing")
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, cache.ErrMiss) {
+		t.Fatalf(`Get(a) after Delete() error = %v, want ErrMiss`, err)
+	}
+
+	_ = c.Set(ctx, "d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.Get(ctx, "d"); !errors.Is(err, cache.ErrMiss) {
+		t.Fatalf(`Get(d) after expiry error = %v, want ErrMiss`, err)
+	}
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRedisPoolSize = 10
+	defaultRedisTimeout  = time.Second
+)
+
+// RedisConfig configures the Redis backend.
+type RedisConfig struct {
+	// Addr is the host:port of the server.
+	Addr     string
+	Password string
+	DB       int
+	// PoolSize limits the idle connections kept open, defaults to 10.
+	PoolSize int
+	// Timeout of a command unless the context ends earlier, defaults to 1s.
+	Timeout time.Duration
+}
+
+// RedisError is an error reply of the server.
+type RedisError struct {
+	Message string
+}
+
+func (e *RedisError) Error() string {
+	return "redis: " + e.Message
+}
+
+// Redis is a Backend speaking the Redis protocol (RESP) to a server
+// shared by the replicas of the service.
+type Redis struct {
+	conf RedisConfig
+	idle chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func NewRedis(conf RedisConfig) *Redis {
+	if conf.PoolSize <= 0 {
+		conf.PoolSize = defaultRedisPoolSize
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultRedisTimeout
+	}
+
+	return &Redis{
+		conf: conf,
+		idle: make(chan *redisConn, conf.PoolSize),
+	}
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := reply.(type) {
+	case nil:
+		return nil, ErrMiss
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := c.do(ctx, args...)
+
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := c.do(ctx, append([]string{"DEL"}, keys...)...)
+
+	return err
+}
+
+// Close closes the idle connections.
+func (c *Redis) Close() error {
+	for {
+		select {
+		case rc := <-c.idle:
+			_ = rc.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends the command and reads its reply. Connections that fail
+// are closed, error replies leave the connection usable.
+func (c *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	rc, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := rc.do(ctx, c.conf.Timeout, args)
+
+	var redisErr *RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		_ = rc.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.idle <- rc:
+	default:
+		_ = rc.conn.Close()
+	}
+
+	return reply, err
+}
+
+// conn takes an idle connection or dials a new one.
+func (c *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case rc := <-c.idle:
+		return rc, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: c.conf.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.conf.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis dial: %w", err)
+	}
+	rc := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+
+	var setup [][]string
+	if c.conf.Password != "" {
+		setup = append(setup, []string{"AUTH", c.conf.Password})
+	}
+	if c.conf.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.conf.DB)})
+	}
+	for _, args := range setup {
+		if _, err := rc.do(ctx, c.conf.Timeout, args); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("redis %s: %w", strings.ToLower(args[0]), err)
+		}
+	}
+
+	return rc, nil
+}
+
+func (rc *redisConn) do(ctx context.Context, timeout time.Duration, args []string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := rc.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(rc.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis write: %w", err)
+	}
+
+	return readReply(rc.rd)
+}
+
+// readReply reads a RESP reply other than an array: a string, an integer,
+// a bulk string as []byte, nil for a null bulk string, or a *RedisError.
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read: %w", err)
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, &RedisError{Message: payload}
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", payload)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, fmt.Errorf("redis read: %w", err)
+		}
+		return buf[:n], nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply %q", line)
+	}
+}
@@ -0,0 +1,179 @@
+package cache_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/cache"
+)
+
+// fakeRedis serves the commands used by the backend from a map.
+type fakeRedis struct {
+	mu       sync.Mutex
+	data     map[string]string
+	password string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf(`listen: %v`, err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	f := &fakeRedis{data: map[string]string{}, password: password}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			authed = args[1] == f.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "GET":
+			v, ok := f.data[args[1]]
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			}
+		case cmd == "SET":
+			f.data[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case cmd == "DEL":
+			var n int
+			for _, key := range args[1:] {
+				if _, ok := f.data[key]; ok {
+					delete(f.data, key)
+					n++
+				}
+			}
+			reply = fmt.Sprintf(":%d\r\n", n)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	fake, addr := startFakeRedis(t, "secret")
+	ctx := context.Background()
+
+	c := cache.NewRedis(cache.RedisConfig{Addr: addr, Password: "secret", Timeout: time.Second})
+	defer c.Close()
+
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, cache.ErrMiss) {
+		t.Fatalf(`Get() of a missing key error = %v, want ErrMiss`, err)
+	}
+	if err := c.Set(ctx, "k", []byte("a\r\nb"), 1500*time.Millisecond); err != nil {
+		t.Fatalf(`Set() error = %v`, err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "a\r\nb" {
+		t.Fatalf(`Get() = %q, %v, want "a\r\nb"`, v, err)
+	}
+	if err := c.Delete(ctx, "k", "other"); err != nil {
+		t.Fatalf(`Delete() error = %v`, err)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, cache.ErrMiss) {
+		t.Fatalf(`Get() after Delete() error = %v, want ErrMiss`, err)
+	}
+
+	fake.mu.Lock()
+	commands := fake.commands
+	fake.mu.Unlock()
+
+	// the connection is authenticated once and reused
+	want := []string{"AUTH secret", "GET k", "SET k a\r\nb PX 1500", "GET k", "DEL k other", "GET k"}
+	if strings.Join(commands, "|") != strings.Join(want, "|") {
+		t.Fatalf(`commands = %q, want %q`, commands, want)
+	}
+}
+
+func TestRedisErrorReply(t *testing.T) {
+	_, addr := startFakeRedis(t, "secret")
+
+	c := cache.NewRedis(cache.RedisConfig{Addr: addr, Password: "wrong"})
+	defer c.Close()
+
+	_, err := c.Get(context.Background(), "k")
+
+	var redisErr *cache.RedisError
+	if !errors.As(err, &redisErr) || !strings.HasPrefix(redisErr.Message, "WRONGPASS") {
+		t.Fatalf(`Get() error = %v, want a WRONGPASS *RedisError`, err)
+	}
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/apikey"
+)
+
+type APIKeyPostgresRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewAPIKeyPostgresRepository(conn *pgxpool.Pool) *APIKeyPostgresRepository {
+	return &APIKeyPostgresRepository{
+		conn: conn,
+	}
+}
+
+// apiKeyColumns lists columns read by scanAPIKey.
+const apiKeyColumns = "id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+func scanAPIKey(row pgx.Row, k *apikey.APIKey) error {
+	return row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.Scopes, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt)
+}
+
+func (r *APIKeyPostgresRepository) Add(ctx context.Context, key *apikey.APIKey) error {
+	query := "INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at) " +
+		"VALUES ($1, $2, $3, $4, $5) RETURNING " + apiKeyColumns
+
+	err := scanAPIKey(connFrom(ctx, r.conn).QueryRow(ctx, query, key.Name, key.Prefix, key.Hash, key.Scopes, key.ExpiresAt), key)
+	if err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *APIKeyPostgresRepository) GetByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE prefix = $1"
+
+	var key apikey.APIKey
+	err := scanAPIKey(connFrom(ctx, r.conn).QueryRow(ctx, query, prefix), &key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyPostgresRepository) List(ctx context.Context) ([]apikey.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys ORDER BY id"
+
+	rows, err := connFrom(ctx, r.conn).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var res []apikey.APIKey
+	for rows.Next() {
+		var key apikey.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		res = append(res, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *APIKeyPostgresRepository) Revoke(ctx context.Context, id int) error {
+	query := "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL"
+
+	tag, err := connFrom(ctx, r.conn).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apikey.ErrNotFound
+	}
+
+	return nil
+}
+
+// Touch updates last_used_at at most once a minute to spare writes on hot keys.
+func (r *APIKeyPostgresRepository) Touch(ctx context.Context, id int) error {
+	query := "UPDATE api_keys SET last_used_at = now() " +
+		"WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')"
+
+	if _, err := connFrom(ctx, r.conn).Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// auditChange is the stored representation of a field change.
+type auditChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+// insertAudit records the change made by the actor of the context
+// within the transaction of the change itself.
+func insertAudit(ctx context.Context, tx pgx.Tx, op string, id int, before, after *company.Company) error {
+	diff := company.Diff(before, after)
+
+	changes := make([]auditChange, 0, len(diff))
+	for _, c := range diff {
+		changes = append(changes, auditChange(c))
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("marshal audit changes: %w", err)
+	}
+
+	actor := company.ActorFrom(ctx)
+	query := "INSERT INTO company_audit (company_id, actor, request_id, operation, changes) " +
+		"VALUES ($1, $2, $3, $4, $5)"
+
+	if _, err := tx.Exec(ctx, query, id, actor.ID, actor.RequestID, op, raw); err != nil {
+		return fmt.Errorf("insert audit: %w", err)
+	}
+
+	return nil
+}
+
+// recordChange writes the audit record of a change and its outbox event if events are enabled.
+func (r *CompanyPostgresRepository) recordChange(
+	ctx context.Context, tx pgx.Tx, op, eventType string, id int, before, after *company.Company,
+) error {
+	if err := insertAudit(ctx, tx, op, id, before, after); err != nil {
+		return err
+	}
+	if !r.events {
+		return nil
+	}
+
+	return insertEvent(ctx, tx, eventType, id, before, after)
+}
+
+func (r *CompanyPostgresRepository) History(ctx context.Context, id int, opts company.HistoryOptions) (company.HistoryPage, error) {
+	f := &filter{}
+	f.add("company_id = $%d", id)
+	if opts.Actor != "" {
+		f.add("actor = $%d", opts.Actor)
+	}
+	if opts.Since != nil {
+		f.add("created_at >= $%d", *opts.Since)
+	}
+	if opts.Until != nil {
+		f.add("created_at <= $%d", *opts.Until)
+	}
+	if opts.Cursor > 0 {
+		f.add("id < $%d", opts.Cursor)
+	}
+
+	query := "SELECT id, company_id, actor, request_id, operation, changes, created_at " +
+		"FROM company_audit" + f.where() +
+		fmt.Sprintf(" ORDER BY id DESC LIMIT %d", opts.PageSize()+1)
+
+	rows, err := readConn(ctx, r.conn, r.replicas).Query(ctx, query, f.args...)
+	if err != nil {
+		return company.HistoryPage{}, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var page company.HistoryPage
+	for rows.Next() {
+		var (
+			rec     company.AuditRecord
+			changes []auditChange
+		)
+		err := rows.Scan(&rec.ID, &rec.CompanyID, &rec.Actor, &rec.RequestID, &rec.Operation, &changes, &rec.CreatedAt)
+		if err != nil {
+			return company.HistoryPage{}, fmt.Errorf("scan failed: %w", err)
+		}
+		for _, c := range changes {
+			rec.Changes = append(rec.Changes, company.FieldChange(c))
+		}
+		page.Items = append(page.Items, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return company.HistoryPage{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	if len(page.Items) > opts.PageSize() {
+		page.Items = page.Items[:opts.PageSize()]
+		page.Next = page.Items[len(page.Items)-1].ID
+	}
+
+	return page, nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// CacheBroadcaster spreads cache invalidations with LISTEN/NOTIFY.
+// Notifications sent within a transaction are delivered on commit.
+type CacheBroadcaster struct {
+	conn    *pgxpool.Pool
+	channel string
+}
+
+func NewCacheBroadcaster(conn *pgxpool.Pool, channel string) *CacheBroadcaster {
+	return &CacheBroadcaster{
+		conn:    conn,
+		channel: channel,
+	}
+}
+
+func (b *CacheBroadcaster) Broadcast(ctx context.Context, msg string) error {
+	if _, err := connFrom(ctx, b.conn).Exec(ctx, "SELECT pg_notify($1, $2)", b.channel, msg); err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
+
+// Listen holds a connection of the pool listening on the channel.
+func (b *CacheBroadcaster) Listen(ctx context.Context, fn func(msg string)) error {
+	conn, err := b.conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire conn: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+	defer func() {
+		// a connection interrupted while waiting is closed and dropped by the pool
+		if !conn.Conn().IsClosed() {
+			_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+		}
+	}()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		fn(n.Payload)
+	}
+}
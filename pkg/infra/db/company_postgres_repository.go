@@ -2,81 +2,470 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 
 	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
 )
 
+// CompanyPostgresRepository stores companies in Postgres,
+// joining the transaction of TxManager carried by the context.
+// Reads outside of a transaction go to the replicas if there are any.
 type CompanyPostgresRepository struct {
-	conn *pgxpool.Pool
+	conn     *pgxpool.Pool
+	replicas *ReplicaSet
+	events   bool
 }
 
-func NewCompanyPostgresRepository(conn *pgxpool.Pool) *CompanyPostgresRepository {
+// NewCompanyPostgresRepository takes the primary pool and optional replicas.
+// Changes write their events to the outbox only if events is set,
+// nothing drains it otherwise.
+func NewCompanyPostgresRepository(conn *pgxpool.Pool, replicas *ReplicaSet, events bool) *CompanyPostgresRepository {
 	return &CompanyPostgresRepository{
-		conn: conn,
+		conn:     conn,
+		replicas: replicas,
+		events:   events,
 	}
 }
 
-func (r *CompanyPostgresRepository) Add(ctx context.Context, raw company.Company) error {
+// companyColumns lists columns read by scanCompany.
+const companyColumns = "id, code, name, country, website, phone, version, deleted_at, deleted_by"
+
+func scanCompany(row pgx.Row, c *company.Company) error {
+	return row.Scan(&c.ID, &c.Code, &c.Name, &c.Country, &c.Website, &c.Phone, &c.Version, &c.DeletedAt, &c.DeletedBy)
+}
+
+func (r *CompanyPostgresRepository) Add(ctx context.Context, raw *company.Company) error {
 	query := "INSERT INTO companies " +
 		"(code, name, country, website, phone) " +
-		"VALUES ($1, $2, $3, $4, $5) RETURNING id"
+		"VALUES ($1, $2, $3, $4, $5) RETURNING " + companyColumns
+
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := scanCompany(tx.QueryRow(ctx, query, raw.Code, raw.Name, raw.Country, raw.Website, raw.Phone), raw)
+		if err != nil {
+			return fmt.Errorf("query exec: %w", translateError(err))
+		}
 
-	err := r.conn.QueryRow(ctx, query, raw, raw.Code, raw.Name, raw.Country, raw.Website, raw.Phone).Scan(&raw.ID)
+		return r.recordChange(ctx, tx, company.OpCreate, company.EventCreated, raw.ID, nil, raw)
+	})
 	if err != nil {
-		return fmt.Errorf("query exec: %w", err)
+		return fmt.Errorf("add tx: %w", err)
 	}
+	r.replicas.wrote(ctx)
 
 	return nil
 }
 
-func (r *CompanyPostgresRepository) List(ctx context.Context, opts company.ListOptions) ([]company.Company, error) {
-	query := "SELECT id, code, name, country, website, phone " +
-		"FROM companies"
+func (r *CompanyPostgresRepository) Get(ctx context.Context, id int, opts company.GetOptions) (company.Company, error) {
+	return r.get(ctx, readConn(ctx, r.conn, r.replicas), id, opts)
+}
+
+func (r *CompanyPostgresRepository) get(ctx context.Context, c conn, id int, opts company.GetOptions) (company.Company, error) {
+	query := "SELECT " + companyColumns + " FROM companies WHERE id = $1 AND ($2 OR deleted_at IS NULL)"
 
-	rows, err := r.conn.Query(ctx, query)
+	var row company.Company
+	err := scanCompany(c.QueryRow(ctx, query, id, opts.IncludeDeleted), &row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return company.Company{}, &company.NotFoundError{ID: id}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return company.Company{}, fmt.Errorf("query failed: %w", err)
 	}
 
+	return row, nil
+}
+
+func (r *CompanyPostgresRepository) List(ctx context.Context, opts company.ListOptions) (company.Page, error) {
+	if err := opts.CheckCursor(); err != nil {
+		return company.Page{}, err
+	}
+
+	f := listFilter(opts)
+
+	order, err := orderBy(opts.Order(), opts.Backward())
+	if err != nil {
+		return company.Page{}, err
+	}
+	if opts.Cursor != nil {
+		keysetFilter(f, opts.Order(), opts.Cursor)
+	}
+
+	query := "SELECT " + companyColumns + " FROM companies" + f.where() +
+		" ORDER BY " + order +
+		fmt.Sprintf(" LIMIT %d", opts.PageSize()+1)
+
+	rows, err := readConn(ctx, r.conn, r.replicas).Query(ctx, query, f.args...)
+	if err != nil {
+		return company.Page{}, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
 	var res []company.Company
 	for rows.Next() {
 		var row company.Company
-		err := rows.Scan(&row.ID, &row.Code, &row.Name, &row.Country, &row.Website, &row.Phone)
-		if err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+		if err := scanCompany(rows, &row); err != nil {
+			return company.Page{}, fmt.Errorf("scan failed: %w", err)
 		}
 		res = append(res, row)
 	}
 
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+	if err := rows.Err(); err != nil {
+		return company.Page{}, fmt.Errorf("query failed: %w", err)
 	}
 
-	return res, nil
+	return opts.Paginate(res), nil
 }
 
+// Export streams the rows as they arrive from the connection,
+// so that memory use does not depend on the size of the result.
+func (r *CompanyPostgresRepository) Export(ctx context.Context, opts company.ListOptions, fn func(company.Company) error) error {
+	f := listFilter(opts)
+
+	order, err := orderBy(opts.Order(), false)
+	if err != nil {
+		return err
+	}
+
+	query := "SELECT " + companyColumns + " FROM companies" + f.where() + " ORDER BY " + order
+
+	rows, err := readConn(ctx, r.conn, r.replicas).Query(ctx, query, f.args...)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row company.Company
+		if err := scanCompany(rows, &row); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CompanyPostgresRepository) Count(ctx context.Context, opts company.ListOptions) (int, error) {
+	f := listFilter(opts)
+	query := "SELECT count(*) FROM companies" + f.where()
+
+	var count int
+	if err := readConn(ctx, r.conn, r.replicas).QueryRow(ctx, query, f.args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// Update overwrites the company. If row.Version is not zero
+// the update is applied only if the stored version matches it.
 func (r *CompanyPostgresRepository) Update(ctx context.Context, row *company.Company) error {
 	query := "UPDATE companies SET code = $1, name = $2, country = $3, website = $4, phone = $5 " +
-		"WHERE id = $6"
+		"WHERE id = $6 AND ($7 = 0 OR version = $7) " +
+		"RETURNING " + companyColumns
 
-	_, err := r.conn.Exec(ctx, query, row.Code, row.Name, row.Country, row.Website, row.Phone, row.ID)
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		before, err := lockCompany(ctx, tx, row.ID, row.Version, company.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		err = scanCompany(tx.QueryRow(ctx, query,
+			row.Code, row.Name, row.Country, row.Website, row.Phone, row.ID, row.Version), row)
+		if err != nil {
+			return fmt.Errorf("query exec: %w", translateError(err))
+		}
+
+		return r.recordChange(ctx, tx, company.OpUpdate, company.EventUpdated, row.ID, &before, row)
+	})
 	if err != nil {
-		return fmt.Errorf("query exec: %w", err)
+		return fmt.Errorf("update tx: %w", err)
 	}
+	r.replicas.wrote(ctx)
 
 	return nil
 }
 
-func (r *CompanyPostgresRepository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM companies WHERE id = $1"
+// Patch changes the fields set in the patch. If version is not zero
+// the patch is applied only if the stored version matches it.
+func (r *CompanyPostgresRepository) Patch(ctx context.Context, id, version int, patch company.Patch) (company.Company, error) {
+	if patch.Empty() {
+		// read the primary, the version check must not see a stale replica
+		row, err := r.get(ctx, connFrom(ctx, r.conn), id, company.GetOptions{})
+		if err == nil && version != 0 && row.Version != version {
+			return company.Company{}, versionMismatch(id, row.Version, version)
+		}
+		return row, err
+	}
+
+	set := &filter{}
+	setColumn := func(col string, val *string) {
+		if val != nil {
+			set.add(col+" = $%d", *val)
+		}
+	}
+	setColumn("code", patch.Code)
+	setColumn("name", patch.Name)
+	setColumn("country", patch.Country)
+	setColumn("website", patch.Website)
+	setColumn("phone", patch.Phone)
+
+	n := len(set.args)
+	query := "UPDATE companies SET " + strings.Join(set.conds, ", ") +
+		fmt.Sprintf(" WHERE id = $%d AND ($%d = 0 OR version = $%d) ", n+1, n+2, n+2) +
+		"RETURNING " + companyColumns
+
+	var row company.Company
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		before, err := lockCompany(ctx, tx, id, version, company.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		err = scanCompany(tx.QueryRow(ctx, query, append(set.args, id, version)...), &row)
+		if err != nil {
+			return fmt.Errorf("query exec: %w", translateError(err))
+		}
+
+		return r.recordChange(ctx, tx, company.OpPatch, company.EventUpdated, id, &before, &row)
+	})
+	if err != nil {
+		return company.Company{}, fmt.Errorf("patch tx: %w", err)
+	}
+	r.replicas.wrote(ctx)
+
+	return row, nil
+}
+
+// Delete marks the company deleted. If version is not zero
+// the company is deleted only if the stored version matches it.
+func (r *CompanyPostgresRepository) Delete(ctx context.Context, id, version int) error {
+	query := "UPDATE companies SET deleted_at = now(), deleted_by = $3 " +
+		"WHERE id = $1 AND ($2 = 0 OR version = $2)"
+
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		before, err := lockCompany(ctx, tx, id, version, company.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, query, id, version, company.ActorFrom(ctx).ID); err != nil {
+			return fmt.Errorf("query exec: %w", translateError(err))
+		}
 
-	_, err := r.conn.Exec(ctx, query, id)
+		return r.recordChange(ctx, tx, company.OpDelete, company.EventDeleted, id, &before, nil)
+	})
 	if err != nil {
-		return fmt.Errorf("query exec: %w", err)
+		return fmt.Errorf("delete tx: %w", err)
 	}
+	r.replicas.wrote(ctx)
 
 	return nil
 }
+
+// Restore undeletes the company. If version is not zero
+// the company is restored only if the stored version matches it.
+func (r *CompanyPostgresRepository) Restore(ctx context.Context, id, version int) (company.Company, error) {
+	query := "UPDATE companies SET deleted_at = NULL, deleted_by = '' " +
+		"WHERE id = $1 AND ($2 = 0 OR version = $2) " +
+		"RETURNING " + companyColumns
+
+	var row company.Company
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		before, err := lockCompany(ctx, tx, id, version, company.GetOptions{IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+		if !before.Deleted() {
+			return &company.ConflictError{Detail: fmt.Sprintf("company %d is not deleted", id)}
+		}
+
+		// the code may have been taken by another company meanwhile
+		if err := scanCompany(tx.QueryRow(ctx, query, id, version), &row); err != nil {
+			return fmt.Errorf("query exec: %w", translateError(err))
+		}
+
+		return r.recordChange(ctx, tx, company.OpRestore, company.EventRestored, id, &before, &row)
+	})
+	if err != nil {
+		return company.Company{}, fmt.Errorf("restore tx: %w", err)
+	}
+	r.replicas.wrote(ctx)
+
+	return row, nil
+}
+
+// Purge removes companies deleted before the time for good,
+// recording the purge in their audit trail on behalf of the actor of the context.
+func (r *CompanyPostgresRepository) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	query := "WITH purged AS (DELETE FROM companies WHERE id IN (" +
+		"SELECT id FROM companies WHERE deleted_at < $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED" +
+		") RETURNING id) " +
+		"INSERT INTO company_audit (company_id, actor, operation, changes) " +
+		"SELECT id, $3, $4, '[]' FROM purged"
+
+	tag, err := connFrom(ctx, r.conn).Exec(ctx, query, deletedBefore, limit, company.ActorFrom(ctx).ID, company.OpPurge)
+	if err != nil {
+		return 0, fmt.Errorf("query exec: %w", err)
+	}
+	r.replicas.wrote(ctx)
+
+	return int(tag.RowsAffected()), nil
+}
+
+// lockCompany reads the company for update within the transaction,
+// so that the change event carries the exact state before the write.
+// If version is not zero it must match the stored one.
+func lockCompany(ctx context.Context, tx pgx.Tx, id, version int, opts company.GetOptions) (company.Company, error) {
+	query := "SELECT " + companyColumns + " FROM companies WHERE id = $1 AND ($2 OR deleted_at IS NULL) FOR UPDATE"
+
+	var row company.Company
+	err := scanCompany(tx.QueryRow(ctx, query, id, opts.IncludeDeleted), &row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return company.Company{}, &company.NotFoundError{ID: id}
+	}
+	if err != nil {
+		return company.Company{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	if version != 0 && row.Version != version {
+		return company.Company{}, versionMismatch(id, row.Version, version)
+	}
+
+	return row, nil
+}
+
+func versionMismatch(id, current, expected int) error {
+	return &company.PreconditionFailedError{
+		Detail: fmt.Sprintf("company %d is at version %d, expected %d", id, current, expected),
+	}
+}
+
+// filter accumulates parameterized conditions of a WHERE clause.
+type filter struct {
+	conds []string
+	args  []interface{}
+}
+
+// add appends a condition, cond must contain a single %d verb
+// that is replaced with the argument placeholder number.
+func (f *filter) add(cond string, val interface{}) {
+	f.args = append(f.args, val)
+	f.conds = append(f.conds, fmt.Sprintf(cond, len(f.args)))
+}
+
+// where returns the WHERE clause or an empty string if there are no conditions.
+func (f *filter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// listFilter builds a filter from the list options.
+func listFilter(opts company.ListOptions) *filter {
+	f := &filter{}
+
+	if opts.Name != "" {
+		f.add(`name ILIKE '%%' || $%d || '%%' ESCAPE '\'`, escapeLike(opts.Name))
+	}
+	if opts.Code != "" {
+		f.add("code = $%d", opts.Code)
+	}
+	if opts.Country != "" {
+		f.add("country = $%d", opts.Country)
+	}
+	if opts.Website != "" {
+		f.add("website = $%d", opts.Website)
+	}
+	if opts.Phone != "" {
+		f.add("phone = $%d", opts.Phone)
+	}
+	if !opts.IncludeDeleted {
+		f.conds = append(f.conds, "deleted_at IS NULL")
+	}
+
+	return f
+}
+
+// sortColumns maps sortable fields to table columns.
+var sortColumns = map[company.SortField]string{
+	company.SortByID:      "id",
+	company.SortByCode:    "code",
+	company.SortByName:    "name",
+	company.SortByCountry: "country",
+	company.SortByWebsite: "website",
+	company.SortByPhone:   "phone",
+}
+
+// orderBy returns the ORDER BY expression, reversed when walking backward.
+func orderBy(order []company.Sort, backward bool) (string, error) {
+	exprs := make([]string, 0, len(order))
+	for _, s := range order {
+		col, ok := sortColumns[s.Field]
+		if !ok {
+			return "", &company.ValidationError{
+				Detail: fmt.Sprintf("cannot sort by %q", s.Field),
+				Err:    company.ErrInvalidSort,
+			}
+		}
+
+		if s.Desc != backward {
+			col += " DESC"
+		}
+		exprs = append(exprs, col)
+	}
+
+	return strings.Join(exprs, ", "), nil
+}
+
+// keysetFilter adds a condition selecting rows after the cursor in the walk direction.
+// For the order (a, b DESC, id) it yields
+// (a > $1) OR (a = $1 AND b < $2) OR (a = $1 AND b = $2 AND id > $3).
+// Columns must be validated by orderBy first.
+func keysetFilter(f *filter, order []company.Sort, cursor *company.Cursor) {
+	var (
+		eqs []string
+		ors []string
+	)
+
+	for i, s := range order {
+		col := sortColumns[s.Field]
+
+		var val interface{} = cursor.ID
+		if i < len(cursor.Keys) {
+			val = cursor.Keys[i]
+		}
+		f.args = append(f.args, val)
+		n := len(f.args)
+
+		op := ">"
+		if s.Desc != cursor.Backward {
+			op = "<"
+		}
+
+		cond := append(eqs[:len(eqs):len(eqs)], fmt.Sprintf("%s %s $%d", col, op, n))
+		ors = append(ors, "("+strings.Join(cond, " AND ")+")")
+		eqs = append(eqs, fmt.Sprintf("%s = $%d", col, n))
+	}
+
+	f.conds = append(f.conds, "("+strings.Join(ors, " OR ")+")")
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
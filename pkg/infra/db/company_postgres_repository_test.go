@@ -0,0 +1,159 @@
+// nolint:testpackage // testing private functions
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgconn"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+func TestListFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      company.ListOptions
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "no filters",
+			wantWhere: " WHERE deleted_at IS NULL",
+		},
+		{
+			name: "include deleted",
+			opts: company.ListOptions{IncludeDeleted: true},
+		},
+		{
+			name:      "exact filters",
+			opts:      company.ListOptions{Code: "C1", Country: "CY"},
+			wantWhere: " WHERE code = $1 AND country = $2 AND deleted_at IS NULL",
+			wantArgs:  []interface{}{"C1", "CY"},
+		},
+		{
+			name:      "escaped name",
+			opts:      company.ListOptions{Name: "50%_off", Phone: "+123", IncludeDeleted: true},
+			wantWhere: ` WHERE name ILIKE '%' || $1 || '%' ESCAPE '\' AND phone = $2`,
+			wantArgs:  []interface{}{`50\%\_off`, "+123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := listFilter(tt.opts)
+			if where := f.where(); where != tt.wantWhere {
+				t.Fatalf(`expected where %q, got %q`, tt.wantWhere, where)
+			}
+			if !reflect.DeepEqual(f.args, tt.wantArgs) {
+				t.Fatalf(`expected args %v, got %v`, tt.wantArgs, f.args)
+			}
+		})
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	opts := company.ListOptions{
+		Sort: []company.Sort{
+			{Field: company.SortByCountry},
+			{Field: company.SortByName, Desc: true},
+		},
+	}
+	tests := []struct {
+		name      string
+		cursor    *company.Cursor
+		wantWhere string
+		wantOrder string
+	}{
+		{
+			name:   "forward",
+			cursor: &company.Cursor{Keys: []string{"CY", "Acme"}, ID: 7},
+			wantWhere: " WHERE ((country > $1) OR (country = $1 AND name < $2) OR " +
+				"(country = $1 AND name = $2 AND id > $3))",
+			wantOrder: "country, name DESC, id",
+		},
+		{
+			name:   "backward",
+			cursor: &company.Cursor{Keys: []string{"CY", "Acme"}, ID: 7, Backward: true},
+			wantWhere: " WHERE ((country < $1) OR (country = $1 AND name > $2) OR " +
+				"(country = $1 AND name = $2 AND id < $3))",
+			wantOrder: "country DESC, name, id DESC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &filter{}
+			keysetFilter(f, opts.Order(), tt.cursor)
+			if where := f.where(); where != tt.wantWhere {
+				t.Fatalf(`expected where %q, got %q`, tt.wantWhere, where)
+			}
+
+			wantArgs := []interface{}{"CY", "Acme", 7}
+			if !reflect.DeepEqual(f.args, wantArgs) {
+				t.Fatalf(`expected args %v, got %v`, wantArgs, f.args)
+			}
+
+			order, err := orderBy(opts.Order(), tt.cursor.Backward)
+			if err != nil {
+				t.Fatalf(`unexpected error %v`, err)
+			}
+			if order != tt.wantOrder {
+				t.Fatalf(`expected order %q, got %q`, tt.wantOrder, order)
+			}
+		})
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	var conflict *company.ConflictError
+	if err := translateError(&pgconn.PgError{Code: pgUniqueViolation}); !errors.As(err, &conflict) {
+		t.Fatalf(`expected conflict error, got %v`, err)
+	}
+
+	var validation *company.ValidationError
+	if err := translateError(&pgconn.PgError{Code: pgStringTooLong}); !errors.As(err, &validation) {
+		t.Fatalf(`expected validation error, got %v`, err)
+	}
+
+	plain := errors.New("plain")
+	if err := translateError(plain); err != plain {
+		t.Fatalf(`expected error %v, got %v`, plain, err)
+	}
+}
+
+func TestPrefixQuery(t *testing.T) {
+	tests := map[string]string{
+		"Acme":            "acme:*",
+		"  acme  co. ":    "acme:* & co:*",
+		"o'brien & sons!": "o:* & brien:* & sons:*",
+		"Müller 24":       "müller:* & 24:*",
+		"!:*|()":          "",
+	}
+
+	for q, want := range tests {
+		if got := prefixQuery(q); got != want {
+			t.Fatalf(`expected query %q for %q, got %q`, want, q, got)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &pgconn.PgError{Code: pgSerializationFailure}, want: true},
+		{err: fmt.Errorf("update tx: %w", &pgconn.PgError{Code: pgDeadlockDetected}), want: true},
+		{err: translateError(&pgconn.PgError{Code: pgUniqueViolation})},
+		{err: errors.New("plain")},
+	}
+
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Fatalf(`expected retryable %v for %v, got %v`, tt.want, tt.err, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// PostgreSQL error codes translated into domain errors.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgStringTooLong       = "22001"
+)
+
+// PostgreSQL error codes of transactions that may succeed if retried.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// translateError maps constraint violations reported by PostgreSQL
+// to the company domain errors. Other errors are returned as is.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return &company.ConflictError{Detail: "company already exists: " + pgErr.Detail, Err: err}
+	case pgForeignKeyViolation:
+		return &company.ConflictError{Detail: "company is referenced or references a missing record: " + pgErr.Detail, Err: err}
+	case pgNotNullViolation, pgCheckViolation, pgStringTooLong:
+		return &company.ValidationError{Detail: pgErr.Message, Err: err}
+	default:
+		return err
+	}
+}
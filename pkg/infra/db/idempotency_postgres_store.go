@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+const (
+	// idempotencyPurgeBatch is the number of expired keys removed along with a new key.
+	idempotencyPurgeBatch = 10
+	// idempotencyClaimTimeout bounds how long the claim of a request
+	// that never finished, e.g. on a crashed instance, blocks its key.
+	// Claims of requests being served are extended, however long they run.
+	idempotencyClaimTimeout = 5 * time.Minute
+)
+
+// idempotencyConn is the part of *pgxpool.Pool used by the store.
+type idempotencyConn interface {
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// IdempotencyPostgresStore implements httpserver.IdempotencyStore.
+// A key is claimed by committing a row without a response, filled in
+// once the request is served, so no connection is held meanwhile.
+// Concurrent requests with a claimed key get httpserver.ErrIdempotencyKeyInUse.
+// Expired keys are removed a few at a time as new keys are added.
+type IdempotencyPostgresStore struct {
+	conn         idempotencyConn
+	ttl          time.Duration
+	claimTimeout time.Duration
+}
+
+func NewIdempotencyPostgresStore(conn *pgxpool.Pool, ttl time.Duration) *IdempotencyPostgresStore {
+	return &IdempotencyPostgresStore{
+		conn:         conn,
+		ttl:          ttl,
+		claimTimeout: idempotencyClaimTimeout,
+	}
+}
+
+func (s *IdempotencyPostgresStore) Lock(
+	ctx context.Context, principal, key string,
+	fn func(stored *httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error),
+) error {
+	stored, err := s.claim(ctx, principal, key)
+	if err != nil {
+		return err
+	}
+
+	stop := func() {}
+	if stored == nil {
+		stop = s.extendClaim(principal, key)
+	}
+	resp, fnErr := fn(stored)
+	stop()
+
+	// the request context is canceled when the client is gone,
+	// the outcome is recorded for its retries anyway
+	ctx = context.Background()
+	switch {
+	case fnErr == nil && resp != nil:
+		err = s.finish(ctx, principal, key, resp)
+	case stored == nil:
+		err = s.release(ctx, principal, key)
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return err
+}
+
+// claim takes the key and returns nil, or returns the response stored for it.
+func (s *IdempotencyPostgresStore) claim(ctx context.Context, principal, key string) (*httpserver.IdempotentResponse, error) {
+	var stored *httpserver.IdempotentResponse
+	err := s.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		stored = nil
+
+		// waits for a concurrent transaction inserting the same key
+		tag, err := tx.Exec(ctx, "INSERT INTO idempotency_keys (principal, key, expires_at) "+
+			"VALUES ($1, $2, now() + $3::interval) ON CONFLICT (principal, key) DO NOTHING",
+			principal, key, s.claimTimeout)
+		if err != nil {
+			return fmt.Errorf("query exec: %w", err)
+		}
+		if tag.RowsAffected() == 1 {
+			return purgeIdempotencyKeys(ctx, tx)
+		}
+
+		var (
+			resp    httpserver.IdempotentResponse
+			expired bool
+		)
+		query := "SELECT fingerprint, status, header, body, expires_at < now() " +
+			"FROM idempotency_keys WHERE principal = $1 AND key = $2 FOR UPDATE"
+		err = tx.QueryRow(ctx, query, principal, key).
+			Scan(&resp.Fingerprint, &resp.Status, &resp.Header, &resp.Body, &expired)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// released by a request finishing meanwhile, a retry claims it
+			return httpserver.ErrIdempotencyKeyInUse
+		}
+		if err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+
+		switch {
+		case expired:
+			// takes over an expired response or an abandoned claim
+			query = "UPDATE idempotency_keys SET fingerprint = '', status = 0, header = '{}', body = '', " +
+				"created_at = now(), expires_at = now() + $3::interval WHERE principal = $1 AND key = $2"
+			if _, err := tx.Exec(ctx, query, principal, key, s.claimTimeout); err != nil {
+				return fmt.Errorf("query exec: %w", err)
+			}
+			return nil
+		case resp.Status == 0:
+			return httpserver.ErrIdempotencyKeyInUse
+		default:
+			stored = &resp
+			return nil
+		}
+	})
+	if errors.Is(err, httpserver.ErrIdempotencyKeyInUse) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("idempotency tx: %w", err)
+	}
+
+	return stored, nil
+}
+
+// extendClaim keeps the claim of the key from expiring
+// until the returned function is called.
+func (s *IdempotencyPostgresStore) extendClaim(principal, key string) (stop func()) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(s.claimTimeout / 3)
+		defer ticker.Stop()
+
+		query := "UPDATE idempotency_keys SET expires_at = now() + $3::interval " +
+			"WHERE principal = $1 AND key = $2 AND status = 0"
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// a failed attempt is made up for by the next ones
+				_, _ = s.conn.Exec(context.Background(), query, principal, key, s.claimTimeout)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// finish stores the response of the claimed key.
+func (s *IdempotencyPostgresStore) finish(ctx context.Context, principal, key string, resp *httpserver.IdempotentResponse) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("marshal header: %w", err)
+	}
+
+	query := "UPDATE idempotency_keys SET fingerprint = $3, status = $4, header = $5, body = $6, " +
+		"created_at = now(), expires_at = now() + $7::interval WHERE principal = $1 AND key = $2"
+	_, err = s.conn.Exec(ctx, query, principal, key, resp.Fingerprint, resp.Status, header, resp.Body, s.ttl)
+	if err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
+
+// release drops the claim of the key, so that the request can be retried.
+func (s *IdempotencyPostgresStore) release(ctx context.Context, principal, key string) error {
+	query := "DELETE FROM idempotency_keys WHERE principal = $1 AND key = $2 AND status = 0"
+	if _, err := s.conn.Exec(ctx, query, principal, key); err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
+
+// purgeIdempotencyKeys removes a batch of expired keys not locked by other requests.
+func purgeIdempotencyKeys(ctx context.Context, tx pgx.Tx) error {
+	query := "DELETE FROM idempotency_keys WHERE (principal, key) IN (" +
+		"SELECT principal, key FROM idempotency_keys WHERE expires_at < now() " +
+		"LIMIT $1 FOR UPDATE SKIP LOCKED)"
+
+	if _, err := tx.Exec(ctx, query, idempotencyPurgeBatch); err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+// nolint:testpackage // testing private functions
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+type fakeIdempotencyKey struct {
+	fingerprint string
+	status      int
+	header      []byte
+	body        []byte
+	expiresAt   time.Time
+}
+
+// fakeIdempotencyConn serves the queries of the store from a map.
+// Transactions hold a mutex as long as they run, standing in for the row locks.
+type fakeIdempotencyConn struct {
+	mu   sync.Mutex
+	keys map[string]*fakeIdempotencyKey
+}
+
+func (c *fakeIdempotencyConn) BeginFunc(_ context.Context, f func(pgx.Tx) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	saved := make(map[string]*fakeIdempotencyKey, len(c.keys))
+	for k, v := range c.keys {
+		row := *v
+		saved[k] = &row
+	}
+	if err := f(&fakeIdempotencyTx{conn: c}); err != nil {
+		c.keys = saved
+		return err
+	}
+
+	return nil
+}
+
+func (c *fakeIdempotencyConn) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.exec(sql, args...)
+}
+
+func (c *fakeIdempotencyConn) exec(sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	switch {
+	case strings.HasPrefix(sql, "INSERT INTO idempotency_keys"):
+		k := args[0].(string) + "/" + args[1].(string)
+		if _, ok := c.keys[k]; ok {
+			return pgconn.CommandTag("INSERT 0 0"), nil
+		}
+		c.keys[k] = &fakeIdempotencyKey{header: []byte("{}"), expiresAt: time.Now().Add(args[2].(time.Duration))}
+		return pgconn.CommandTag("INSERT 0 1"), nil
+	case strings.HasPrefix(sql, "DELETE FROM idempotency_keys WHERE (principal, key) IN"):
+		for k, row := range c.keys {
+			if row.expiresAt.Before(time.Now()) {
+				delete(c.keys, k)
+			}
+		}
+		return pgconn.CommandTag("DELETE"), nil
+	case strings.HasPrefix(sql, "DELETE FROM idempotency_keys"):
+		k := args[0].(string) + "/" + args[1].(string)
+		if row, ok := c.keys[k]; ok && row.status == 0 {
+			delete(c.keys, k)
+		}
+		return pgconn.CommandTag("DELETE"), nil
+	case strings.HasPrefix(sql, "UPDATE idempotency_keys SET expires_at"):
+		row, ok := c.keys[args[0].(string)+"/"+args[1].(string)]
+		if !ok || row.status != 0 {
+			return pgconn.CommandTag("UPDATE 0"), nil
+		}
+		row.expiresAt = time.Now().Add(args[2].(time.Duration))
+		return pgconn.CommandTag("UPDATE 1"), nil
+	case strings.HasPrefix(sql, "UPDATE idempotency_keys"):
+		row, ok := c.keys[args[0].(string)+"/"+args[1].(string)]
+		if !ok {
+			return pgconn.CommandTag("UPDATE 0"), nil
+		}
+		if len(args) == 3 {
+			*row = fakeIdempotencyKey{header: []byte("{}"), expiresAt: time.Now().Add(args[2].(time.Duration))}
+			return pgconn.CommandTag("UPDATE 1"), nil
+		}
+		*row = fakeIdempotencyKey{
+			fingerprint: args[2].(string),
+			status:      args[3].(int),
+			header:      args[4].([]byte),
+			body:        args[5].([]byte),
+			expiresAt:   time.Now().Add(args[6].(time.Duration)),
+		}
+		return pgconn.CommandTag("UPDATE 1"), nil
+	default:
+		return nil, errors.New("unexpected query " + sql)
+	}
+}
+
+type fakeIdempotencyTx struct {
+	pgx.Tx
+	conn *fakeIdempotencyConn
+}
+
+func (tx *fakeIdempotencyTx) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.conn.exec(sql, args...)
+}
+
+func (tx *fakeIdempotencyTx) QueryRow(_ context.Context, _ string, args ...interface{}) pgx.Row {
+	return fakeIdempotencyRow{row: tx.conn.keys[args[0].(string)+"/"+args[1].(string)]}
+}
+
+type fakeIdempotencyRow struct {
+	row *fakeIdempotencyKey
+}
+
+func (r fakeIdempotencyRow) Scan(dest ...interface{}) error {
+	if r.row == nil {
+		return pgx.ErrNoRows
+	}
+
+	*dest[0].(*string) = r.row.fingerprint
+	*dest[1].(*int) = r.row.status
+	if err := json.Unmarshal(r.row.header, dest[2].(*http.Header)); err != nil {
+		return err
+	}
+	*dest[3].(*[]byte) = r.row.body
+	*dest[4].(*bool) = r.row.expiresAt.Before(time.Now())
+
+	return nil
+}
+
+func TestIdempotencyPostgresStore(t *testing.T) {
+	conn := &fakeIdempotencyConn{keys: map[string]*fakeIdempotencyKey{}}
+	s := &IdempotencyPostgresStore{conn: conn, ttl: time.Hour, claimTimeout: time.Minute}
+	ctx := context.Background()
+
+	created := &httpserver.IdempotentResponse{
+		Fingerprint: "f1",
+		Status:      http.StatusCreated,
+		Header:      http.Header{"Location": {"/v1/companies/7"}},
+		Body:        []byte(`{"id":7}`),
+	}
+
+	// the key is claimed while the request is served, without holding a connection
+	err := s.Lock(ctx, "alice", "k1", func(stored *httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+		if stored != nil {
+			t.Fatalf(`expected a new key, got %+v`, stored)
+		}
+
+		err := s.Lock(ctx, "alice", "k1", func(*httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+			t.Fatalf(`unexpected call for a claimed key`)
+			return nil, nil
+		})
+		if !errors.Is(err, httpserver.ErrIdempotencyKeyInUse) {
+			t.Fatalf(`Lock() of a claimed key error = %v, want ErrIdempotencyKeyInUse`, err)
+		}
+
+		// keys are scoped by the principal
+		return nil, s.Lock(ctx, "bob", "k1", func(stored *httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+			if stored != nil {
+				t.Fatalf(`expected a new key of another principal, got %+v`, stored)
+			}
+			return nil, nil
+		})
+	})
+	if err != nil {
+		t.Fatalf(`Lock() error = %v`, err)
+	}
+
+	// released keys are claimed again and keep the response
+	err = s.Lock(ctx, "alice", "k1", func(stored *httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+		if stored != nil {
+			t.Fatalf(`expected a released key, got %+v`, stored)
+		}
+		return created, nil
+	})
+	if err != nil {
+		t.Fatalf(`Lock() error = %v`, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err = s.Lock(ctx, "alice", "k1", func(stored *httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+			if stored == nil || stored.Fingerprint != created.Fingerprint || stored.Status != created.Status ||
+				stored.Header.Get("Location") != "/v1/companies/7" || string(stored.Body) != string(created.Body) {
+				t.Fatalf(`expected the stored response replayed, got %+v`, stored)
+			}
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf(`Lock() error = %v`, err)
+		}
+	}
+
+	// expired responses and claims abandoned by a crashed instance are taken over
+	conn.keys["alice/k1"].expiresAt = time.Now().Add(-time.Second)
+	conn.keys["alice/k2"] = &fakeIdempotencyKey{header: []byte("{}"), expiresAt: time.Now().Add(-time.Second)}
+	for _, key := range []string{"k1", "k2"} {
+		err = s.Lock(ctx, "alice", key, func(stored *httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+			if stored != nil {
+				t.Fatalf(`expected an expired key taken over, got %+v`, stored)
+			}
+			return nil, errors.New("handler failed")
+		})
+		if err == nil || err.Error() != "handler failed" {
+			t.Fatalf(`Lock() error = %v, want the error of fn`, err)
+		}
+	}
+	if len(conn.keys) != 0 {
+		t.Fatalf(`expected failed claims released, got %d keys`, len(conn.keys))
+	}
+
+	// claims of requests served longer than the claim timeout are extended
+	s.claimTimeout = 30 * time.Millisecond
+	err = s.Lock(ctx, "alice", "k3", func(*httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error) {
+		time.Sleep(4 * s.claimTimeout)
+
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+		if row := conn.keys["alice/k3"]; !row.expiresAt.After(time.Now()) {
+			t.Fatalf(`expected the claim extended, expired at %v`, row.expiresAt)
+		}
+		return created, nil
+	})
+	if err != nil {
+		t.Fatalf(`Lock() error = %v`, err)
+	}
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// errImportRollback rolls back an atomic import that had failed rows.
+var errImportRollback = errors.New("import rolled back")
+
+// copySource feeds the valid rows of an import source to CopyFrom
+// and collects the rejected ones.
+type copySource struct {
+	source   company.ImportSource
+	row      company.ImportRow
+	rejected []company.ImportResult
+}
+
+func (s *copySource) Next() bool {
+	for s.source.Next() {
+		s.row = s.source.Row()
+		if s.row.Err == nil {
+			return true
+		}
+
+		s.rejected = append(s.rejected, company.ImportResult{
+			Line:   s.row.Line,
+			Status: company.ImportRejected,
+			Err:    s.row.Err,
+		})
+	}
+
+	return false
+}
+
+func (s *copySource) Values() ([]interface{}, error) {
+	c := s.row.Company
+	return []interface{}{s.row.Line, c.Code, c.Name, c.Country, c.Website, c.Phone}, nil
+}
+
+func (s *copySource) Err() error {
+	return s.source.Err()
+}
+
+// Import copies the rows into a staging table and merges them into companies.
+// Accepted rows get their IDs assigned in the staging table first,
+// so that the report can map the lines to the created companies.
+// Every created company gets an audit record and an outbox event as single creates do.
+func (r *CompanyPostgresRepository) Import(ctx context.Context, source company.ImportSource, atomic bool) (company.ImportReport, error) {
+	var report company.ImportReport
+
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		report = company.ImportReport{}
+
+		query := "CREATE TEMP TABLE import_companies (" +
+			"line INTEGER PRIMARY KEY, id INTEGER, " +
+			"code TEXT, name TEXT, country TEXT, website TEXT, phone TEXT" +
+			") ON COMMIT DROP"
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("create staging table: %w", err)
+		}
+
+		src := &copySource{source: source}
+		columns := []string{"line", "code", "name", "country", "website", "phone"}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"import_companies"}, columns, src); err != nil {
+			return fmt.Errorf("copy rows: %w", translateError(err))
+		}
+
+		// the first row of a code not taken by a live company wins
+		query = "UPDATE import_companies s SET id = nextval(pg_get_serial_sequence('companies', 'id')) " +
+			"FROM (SELECT line, code, row_number() OVER (PARTITION BY code ORDER BY line) AS n FROM import_companies) r " +
+			"WHERE s.line = r.line AND (r.code = '' OR (r.n = 1 AND NOT EXISTS (" +
+			"SELECT 1 FROM companies c WHERE c.code = r.code AND c.deleted_at IS NULL)))"
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("assign ids: %w", err)
+		}
+
+		query = "INSERT INTO companies (id, code, name, country, website, phone) " +
+			"SELECT id, code, name, country, website, phone FROM import_companies " +
+			"WHERE id IS NOT NULL ORDER BY line"
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("merge rows: %w", translateError(err))
+		}
+
+		if err := recordImport(ctx, tx, r.events); err != nil {
+			return err
+		}
+
+		results, err := importResults(ctx, tx, src.rejected)
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			report.Add(res)
+		}
+
+		if atomic && report.Accepted != len(report.Rows) {
+			return errImportRollback
+		}
+		report.Committed = true
+
+		return nil
+	})
+	if errors.Is(err, errImportRollback) {
+		return report, nil
+	}
+	if err != nil {
+		return company.ImportReport{}, fmt.Errorf("import tx: %w", err)
+	}
+	r.replicas.wrote(ctx)
+
+	return report, nil
+}
+
+// recordImport writes the audit records and, if events are enabled, the outbox events
+// of the imported companies, matching the payloads written by insertAudit and insertEvent.
+func recordImport(ctx context.Context, tx pgx.Tx, events bool) error {
+	actor := company.ActorFrom(ctx)
+
+	query := "INSERT INTO company_audit (company_id, actor, request_id, operation, changes) " +
+		"SELECT s.id, $1, $2, $3, (" +
+		"SELECT coalesce(jsonb_agg(jsonb_build_object('field', f.field, 'before', NULL, 'after', f.value)), '[]') " +
+		"FROM (VALUES ('code', s.code), ('name', s.name), ('country', s.country), " +
+		"('website', s.website), ('phone', s.phone)) AS f (field, value) WHERE f.value <> ''" +
+		") FROM import_companies s WHERE s.id IS NOT NULL ORDER BY s.line"
+	if _, err := tx.Exec(ctx, query, actor.ID, actor.RequestID, company.OpImport); err != nil {
+		return fmt.Errorf("insert audit: %w", err)
+	}
+	if !events {
+		return nil
+	}
+
+	query = "INSERT INTO outbox (event_type, schema_version, aggregate_id, payload) " +
+		"SELECT $1, $2, c.id, jsonb_build_object('before', NULL, 'after', jsonb_build_object(" +
+		"'id', c.id, 'code', c.code, 'name', c.name, 'country', c.country, " +
+		"'website', c.website, 'phone', c.phone, 'version', c.version)) " +
+		"FROM import_companies s JOIN companies c ON c.id = s.id ORDER BY s.line"
+	if _, err := tx.Exec(ctx, query, company.EventCreated, company.EventSchemaVersion); err != nil {
+		return fmt.Errorf("insert events: %w", err)
+	}
+
+	return nil
+}
+
+// importResults merges the outcome of the staged rows with the rejected ones ordered by line.
+func importResults(ctx context.Context, tx pgx.Tx, rejected []company.ImportResult) ([]company.ImportResult, error) {
+	rows, err := tx.Query(ctx, "SELECT line, id FROM import_companies ORDER BY line")
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	res := rejected
+	for rows.Next() {
+		var (
+			line int
+			id   *int
+		)
+		if err := rows.Scan(&line, &id); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		if id == nil {
+			res = append(res, company.ImportResult{Line: line, Status: company.ImportDuplicate})
+			continue
+		}
+		res = append(res, company.ImportResult{Line: line, Status: company.ImportAccepted, ID: *id})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Line < res[j].Line })
+
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// eventCompany is the company representation in change event payloads.
+type eventCompany struct {
+	ID      int    `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Country string `json:"country"`
+	Website string `json:"website"`
+	Phone   string `json:"phone"`
+	Version int    `json:"version"`
+}
+
+type eventPayload struct {
+	Before *eventCompany `json:"before"`
+	After  *eventCompany `json:"after"`
+}
+
+func toEventCompany(c *company.Company) *eventCompany {
+	if c == nil {
+		return nil
+	}
+
+	return &eventCompany{
+		ID:      c.ID,
+		Code:    c.Code,
+		Name:    c.Name,
+		Country: c.Country,
+		Website: c.Website,
+		Phone:   c.Phone,
+		Version: c.Version,
+	}
+}
+
+// insertEvent writes a change event into the outbox within the transaction
+// of the change itself. Before is nil for created and after for deleted companies.
+// The company row is written or locked first, so the ids of the events
+// of a company follow the commits of their changes.
+func insertEvent(ctx context.Context, tx pgx.Tx, eventType string, id int, before, after *company.Company) error {
+	payload, err := json.Marshal(eventPayload{
+		Before: toEventCompany(before),
+		After:  toEventCompany(after),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	query := "INSERT INTO outbox (event_type, schema_version, aggregate_id, payload) " +
+		"VALUES ($1, $2, $3, $4)"
+
+	if _, err := tx.Exec(ctx, query, eventType, company.EventSchemaVersion, id, payload); err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+const (
+	defaultCheckInterval = 5 * time.Second
+	defaultCheckTimeout  = time.Second
+)
+
+// ReplicaConfig configures the routing of reads to replicas.
+type ReplicaConfig struct {
+	// CheckInterval between health checks, defaults to 5s.
+	CheckInterval time.Duration
+	// CheckTimeout of a health check, defaults to 1s.
+	CheckTimeout time.Duration
+	// MaxLag drops replicas lagging behind the primary by more, zero disables the check.
+	MaxLag time.Duration
+	// ReadYourWrites sends the reads of an actor to the primary
+	// for the duration after its last write, zero disables it.
+	ReadYourWrites time.Duration
+}
+
+// ReplicaSet spreads reads over healthy replicas, falling back
+// to the primary when there is none. Replicas are out of rotation
+// until their first successful health check.
+type ReplicaSet struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	logger   log.Interface
+	conf     ReplicaConfig
+	next     uint32
+
+	mu sync.Mutex
+	// writes holds the time of the last write of every actor
+	// within the read-your-writes window.
+	writes map[string]time.Time
+}
+
+type replica struct {
+	// index in the configured list, used in logs
+	index   int
+	conn    *pgxpool.Pool
+	healthy int32
+}
+
+// NewReplicaSet takes the replica pools in the configured order.
+// The pools may connect lazily, an unreachable replica stays
+// out of rotation until it passes a health check.
+func NewReplicaSet(primary *pgxpool.Pool, replicas []*pgxpool.Pool, logger log.Interface, conf ReplicaConfig) *ReplicaSet {
+	if conf.CheckInterval <= 0 {
+		conf.CheckInterval = defaultCheckInterval
+	}
+	if conf.CheckTimeout <= 0 {
+		conf.CheckTimeout = defaultCheckTimeout
+	}
+
+	s := &ReplicaSet{
+		primary: primary,
+		logger:  logger,
+		conf:    conf,
+		writes:  map[string]time.Time{},
+	}
+	for i, conn := range replicas {
+		s.replicas = append(s.replicas, &replica{index: i, conn: conn})
+	}
+
+	return s
+}
+
+// reader returns the pool to read from on behalf of the actor of the context.
+// A nil set and contexts marked by company.WithPrimaryRead read from the primary.
+func (s *ReplicaSet) reader(ctx context.Context, primary *pgxpool.Pool) *pgxpool.Pool {
+	if s == nil || company.PrimaryRead(ctx) || s.recentWriter(ctx) {
+		return primary
+	}
+
+	n := uint32(len(s.replicas))
+	start := atomic.AddUint32(&s.next, 1)
+	for i := uint32(0); i < n; i++ {
+		r := s.replicas[(start+i)%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.conn
+		}
+	}
+
+	return primary
+}
+
+// wrote records a write of the actor of the context for read-your-writes.
+// Anonymous writes are not tracked, they would send every anonymous read
+// to the primary.
+func (s *ReplicaSet) wrote(ctx context.Context) {
+	actor := company.ActorFrom(ctx).ID
+	if s == nil || s.conf.ReadYourWrites <= 0 || actor == "" {
+		return
+	}
+
+	s.mu.Lock()
+	s.writes[actor] = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *ReplicaSet) recentWriter(ctx context.Context) bool {
+	actor := company.ActorFrom(ctx).ID
+	if s.conf.ReadYourWrites <= 0 || actor == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	at, ok := s.writes[actor]
+	s.mu.Unlock()
+
+	return ok && time.Since(at) < s.conf.ReadYourWrites
+}
+
+// Run checks the replicas until the context is canceled.
+func (s *ReplicaSet) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.conf.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Check(ctx)
+		s.forgetWrites()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check puts the replicas that are reachable and within the lag into rotation
+// and drops the others.
+func (s *ReplicaSet) Check(ctx context.Context) {
+	var (
+		lsn    string
+		lsnErr error
+	)
+	if s.conf.MaxLag > 0 {
+		lsn, lsnErr = s.primaryLSN(ctx)
+	}
+
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+
+			err := lsnErr
+			if err == nil {
+				err = s.check(ctx, r, lsn)
+			}
+			healthy := int32(0)
+			if err == nil {
+				healthy = 1
+			}
+
+			if old := atomic.SwapInt32(&r.healthy, healthy); old != healthy {
+				if err != nil {
+					s.logger.Error(err, "replica dropped from rotation", "replica", r.index)
+				} else {
+					s.logger.Info("replica back in rotation", "replica", r.index)
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+// errNotReplica is reported by a replica URI pointing at a primary.
+var errNotReplica = errors.New("server is not in recovery")
+
+// primaryLSN returns the current write-ahead log position of the primary.
+func (s *ReplicaSet) primaryLSN(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.conf.CheckTimeout)
+	defer cancel()
+
+	var lsn string
+	if err := s.primary.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return "", fmt.Errorf("query primary wal position: %w", err)
+	}
+
+	return lsn, nil
+}
+
+// check verifies that the replica is in recovery and, when the lag is limited,
+// that it replayed the primary log up to lsn or its last replayed transaction
+// is within the lag. The replay timestamp stands still while the primary is idle,
+// so it is only taken into account for a replica behind the primary.
+func (s *ReplicaSet) check(ctx context.Context, r *replica, lsn string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.conf.CheckTimeout)
+	defer cancel()
+
+	var (
+		recovery bool
+		lag      *float64
+		err      error
+	)
+	if s.conf.MaxLag > 0 {
+		query := "SELECT pg_is_in_recovery(), CASE " +
+			"WHEN pg_last_wal_replay_lsn() >= $1::pg_lsn THEN 0 " +
+			"ELSE extract(epoch FROM now() - pg_last_xact_replay_timestamp()) END::float8"
+		err = r.conn.QueryRow(ctx, query, lsn).Scan(&recovery, &lag)
+	} else {
+		err = r.conn.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&recovery)
+	}
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	if !recovery {
+		return errNotReplica
+	}
+	if s.conf.MaxLag <= 0 {
+		return nil
+	}
+
+	if lag == nil {
+		return errors.New("replica is behind the primary and replayed no transaction yet")
+	}
+	if lag := time.Duration(*lag * float64(time.Second)); lag > s.conf.MaxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag, s.conf.MaxLag)
+	}
+
+	return nil
+}
+
+// forgetWrites drops the writes out of the read-your-writes window.
+func (s *ReplicaSet) forgetWrites() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for actor, at := range s.writes {
+		if time.Since(at) >= s.conf.ReadYourWrites {
+			delete(s.writes, actor)
+		}
+	}
+}
+
+// readConn returns the transaction carried by the context, or the pool to read from.
+func readConn(ctx context.Context, primary *pgxpool.Pool, replicas *ReplicaSet) conn {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return replicas.reader(ctx, primary)
+}
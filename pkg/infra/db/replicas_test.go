@@ -0,0 +1,94 @@
+// nolint:testpackage // testing private functions
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+func lazyPool(t *testing.T, uri string) *pgxpool.Pool {
+	t.Helper()
+
+	conf, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		t.Fatalf(`ParseConfig() error = %v`, err)
+	}
+	conf.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), conf)
+	if err != nil {
+		t.Fatalf(`ConnectConfig() error = %v`, err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func TestReplicaSetReader(t *testing.T) {
+	primary := lazyPool(t, "postgres://primary/db")
+	first, second := lazyPool(t, "postgres://first/db"), lazyPool(t, "postgres://second/db")
+
+	s := NewReplicaSet(primary, []*pgxpool.Pool{first, second}, log.Logger,
+		ReplicaConfig{ReadYourWrites: time.Hour})
+	ctx := context.Background()
+
+	var nilSet *ReplicaSet
+	if got := nilSet.reader(ctx, primary); got != primary {
+		t.Fatalf(`expected the primary without replicas`)
+	}
+	if got := s.reader(ctx, primary); got != primary {
+		t.Fatalf(`expected the primary before the replicas are checked`)
+	}
+
+	for _, r := range s.replicas {
+		r.healthy = 1
+	}
+	seen := map[*pgxpool.Pool]bool{}
+	for i := 0; i < 4; i++ {
+		seen[s.reader(ctx, primary)] = true
+	}
+	if len(seen) != 2 || seen[primary] {
+		t.Fatalf(`expected reads spread over both replicas, got %d pools`, len(seen))
+	}
+
+	s.replicas[0].healthy = 0
+	healthy := s.replicas[1].conn
+	for i := 0; i < 2; i++ {
+		if got := s.reader(ctx, primary); got != healthy {
+			t.Fatalf(`expected the healthy replica only`)
+		}
+	}
+	if got := s.reader(company.WithPrimaryRead(ctx), primary); got != primary {
+		t.Fatalf(`expected the primary for a read asking for it`)
+	}
+
+	// the writer reads its writes from the primary, others still read the replica
+	alice := company.WithActor(ctx, company.Actor{ID: "alice"})
+	s.wrote(alice)
+	if got := s.reader(alice, primary); got != primary {
+		t.Fatalf(`expected the primary right after a write`)
+	}
+	if got := s.reader(company.WithActor(ctx, company.Actor{ID: "bob"}), primary); got != healthy {
+		t.Fatalf(`expected the replica for another actor`)
+	}
+	s.wrote(ctx)
+	if got := s.reader(ctx, primary); got != healthy || len(s.writes) != 1 {
+		t.Fatalf(`expected anonymous writes not tracked, got %v`, s.writes)
+	}
+
+	s.conf.ReadYourWrites = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	s.forgetWrites()
+	if got := s.reader(alice, primary); got != healthy {
+		t.Fatalf(`expected the replica after the read-your-writes window`)
+	}
+	if len(s.writes) != 0 {
+		t.Fatalf(`expected writes out of the window forgotten, got %v`, s.writes)
+	}
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// searchSimilarity is the word similarity threshold of misspelled names,
+// the <% operator applies it using the trigram index.
+const searchSimilarity = "0.3"
+
+// searchHeadline are the ts_headline options highlighting every match of a name.
+var searchHeadline = fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true",
+	company.HighlightStart, company.HighlightStop)
+
+// Search matches the query words as prefixes of the words of names and codes,
+// along with names similar to the query. Hits are ranked by the sum
+// of the text rank and the word similarity of the name.
+func (r *CompanyPostgresRepository) Search(ctx context.Context, opts company.SearchOptions) (company.SearchPage, error) {
+	rank, id, err := opts.CheckCursor()
+	if err != nil {
+		return company.SearchPage{}, err
+	}
+
+	// $1 is the text search query and $2 the query as typed
+	f := &filter{
+		conds: []string{"deleted_at IS NULL", "(search_vector @@ q OR $2 <% name)"},
+		args:  []interface{}{prefixQuery(opts.Query), opts.Query},
+	}
+	if opts.Country != "" {
+		f.add("country = $%d", opts.Country)
+	}
+
+	hits := "SELECT " + companyColumns + ", " +
+		"(ts_rank(search_vector, q) + word_similarity($2, name))::float8 AS rank " +
+		"FROM companies, to_tsquery('simple', $1) q" + f.where()
+
+	var after string
+	if opts.Cursor != nil {
+		f.args = append(f.args, rank, id)
+		n := len(f.args)
+		after = fmt.Sprintf(" WHERE rank < $%d OR (rank = $%d AND id > $%d)", n-1, n-1, n)
+	}
+
+	query := "SELECT " + companyColumns + ", rank, " +
+		"ts_headline('simple', name, to_tsquery('simple', $1), '" + searchHeadline + "') " +
+		"FROM (" + hits + ") hits" + after +
+		fmt.Sprintf(" ORDER BY rank DESC, id LIMIT %d", opts.PageSize()+1)
+
+	var res []company.SearchHit
+	err = readConn(ctx, r.conn, r.replicas).BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", searchSimilarity)
+		if err != nil {
+			return fmt.Errorf("query exec: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, query, f.args...)
+		if err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				hit company.SearchHit
+				c   = &hit.Company
+			)
+			err := rows.Scan(&c.ID, &c.Code, &c.Name, &c.Country, &c.Website, &c.Phone, &c.Version,
+				&c.DeletedAt, &c.DeletedBy, &hit.Rank, &hit.Highlight)
+			if err != nil {
+				return fmt.Errorf("scan failed: %w", err)
+			}
+			res = append(res, hit)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return company.SearchPage{}, fmt.Errorf("search tx: %w", err)
+	}
+
+	return opts.Paginate(res), nil
+}
+
+// prefixQuery turns the words of the query into a tsquery matching
+// words starting with every one of them, e.g. "Acme co." into "acme:* & co:*".
+// Other characters are dropped, so the result is always a valid tsquery.
+func prefixQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/transaction"
+)
+
+// conn is implemented by both the pool and a transaction.
+// BeginFunc of a transaction creates a savepoint, so that
+// the writes of a repository nest into a transaction of TxManager.
+type conn interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+}
+
+type txKey struct{}
+
+// connFrom returns the transaction carried by the context, or the pool if there is none.
+func connFrom(ctx context.Context, pool *pgxpool.Pool) conn {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return pool
+}
+
+// TxConfig configures the transactions of TxManager.
+type TxConfig struct {
+	// Isolation is the isolation level of top level transactions,
+	// e.g. "serializable". Empty means the server default.
+	Isolation string
+	// MaxAttempts limits the attempts of a transaction failing
+	// on serialization or deadlock. Zero means 3.
+	MaxAttempts int
+	// RetryDelay is the base of the jittered delay between attempts. Zero means 10ms.
+	RetryDelay time.Duration
+}
+
+// txBeginner is the part of *pgxpool.Pool starting the transactions of TxManager.
+type txBeginner interface {
+	BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error
+}
+
+// TxManager implements transaction.Manager with Postgres transactions.
+type TxManager struct {
+	conn txBeginner
+	opts pgx.TxOptions
+	conf TxConfig
+}
+
+func NewTxManager(conn *pgxpool.Pool, conf TxConfig) (*TxManager, error) {
+	iso := pgx.TxIsoLevel(conf.Isolation)
+	switch iso {
+	case "", pgx.ReadCommitted, pgx.RepeatableRead, pgx.Serializable:
+	default:
+		return nil, fmt.Errorf("unknown isolation level %q", conf.Isolation)
+	}
+
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = 3
+	}
+	if conf.RetryDelay <= 0 {
+		conf.RetryDelay = 10 * time.Millisecond
+	}
+
+	return &TxManager{
+		conn: conn,
+		opts: pgx.TxOptions{IsoLevel: iso},
+		conf: conf,
+	}, nil
+}
+
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx.BeginFunc(ctx, func(savepoint pgx.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, savepoint))
+		})
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.conn.BeginTxFunc(ctx, m.opts, func(tx pgx.Tx) error {
+			return fn(context.WithValue(transaction.WithActive(ctx), txKey{}, tx))
+		})
+		if err == nil || attempt >= m.conf.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := m.conf.RetryDelay * time.Duration(attempt)
+		delay += time.Duration(rand.Int63n(int64(delay))) // nolint:gosec // jitter needs no crypto
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable reports whether the transaction failed on a conflict
+// with a concurrent one and may succeed if run again.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
@@ -0,0 +1,163 @@
+// nolint:testpackage // testing private functions
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/transaction"
+)
+
+// fakeTxDB records the statements of committed transactions.
+// Rolling back a transaction or a savepoint drops its statements.
+type fakeTxDB struct {
+	attempts  int
+	committed []string
+}
+
+func (db *fakeTxDB) BeginTxFunc(_ context.Context, _ pgx.TxOptions, f func(pgx.Tx) error) error {
+	db.attempts++
+
+	tx := &fakeTx{}
+	if err := f(tx); err != nil {
+		return err
+	}
+	db.committed = append(db.committed, tx.statements...)
+
+	return nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+	statements []string
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	tx.statements = append(tx.statements, sql)
+	return pgconn.CommandTag("INSERT 0 1"), nil
+}
+
+func (tx *fakeTx) BeginFunc(_ context.Context, f func(pgx.Tx) error) error {
+	savepoint := &fakeTx{}
+	if err := f(savepoint); err != nil {
+		return err
+	}
+	tx.statements = append(tx.statements, savepoint.statements...)
+
+	return nil
+}
+
+func exec(ctx context.Context, t *testing.T, sql string) {
+	t.Helper()
+
+	if _, err := connFrom(ctx, nil).Exec(ctx, sql); err != nil {
+		t.Fatalf(`Exec() error = %v`, err)
+	}
+}
+
+func TestTxManagerNested(t *testing.T) {
+	db := &fakeTxDB{}
+	m := &TxManager{conn: db, conf: TxConfig{MaxAttempts: 3, RetryDelay: time.Millisecond}}
+	errInner := errors.New("inner failed")
+
+	err := m.Do(context.Background(), func(ctx context.Context) error {
+		if !transaction.Active(ctx) {
+			t.Fatalf(`expected the context marked as carrying a transaction`)
+		}
+		exec(ctx, t, "a")
+
+		// a failed nested call rolls back to its savepoint only
+		err := m.Do(ctx, func(ctx context.Context) error {
+			exec(ctx, t, "b")
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Fatalf(`nested Do() error = %v, want %v`, err, errInner)
+		}
+
+		err = m.Do(ctx, func(ctx context.Context) error {
+			exec(ctx, t, "c")
+			return nil
+		})
+		if err != nil {
+			t.Fatalf(`nested Do() error = %v`, err)
+		}
+
+		exec(ctx, t, "d")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(`Do() error = %v`, err)
+	}
+
+	if got := strings.Join(db.committed, ","); got != "a,c,d" || db.attempts != 1 {
+		t.Fatalf(`committed %q in %d attempts, want "a,c,d" in one`, got, db.attempts)
+	}
+}
+
+func TestTxManagerRetry(t *testing.T) {
+	conflict := &pgconn.PgError{Code: pgSerializationFailure}
+	deadlock := &pgconn.PgError{Code: pgDeadlockDetected}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantCode     string
+		wantAttempts int
+	}{
+		{
+			name:         "success",
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "serialization failure and deadlock retried",
+			errs:         []error{conflict, deadlock, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "attempts exhausted",
+			errs:         []error{conflict, conflict, conflict, nil},
+			wantCode:     pgSerializationFailure,
+			wantAttempts: 3,
+		},
+		{
+			name:         "other errors not retried",
+			errs:         []error{&pgconn.PgError{Code: pgUniqueViolation}, nil},
+			wantCode:     pgUniqueViolation,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeTxDB{}
+			m := &TxManager{conn: db, conf: TxConfig{MaxAttempts: 3, RetryDelay: time.Millisecond}}
+
+			err := m.Do(context.Background(), func(ctx context.Context) error {
+				exec(ctx, t, "a")
+				return tt.errs[db.attempts-1]
+			})
+
+			var pgErr *pgconn.PgError
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Fatalf(`Do() error = %v`, err)
+			case tt.wantCode != "" && (!errors.As(err, &pgErr) || pgErr.Code != tt.wantCode):
+				t.Fatalf(`Do() error = %v, want code %s`, err, tt.wantCode)
+			}
+			if db.attempts != tt.wantAttempts {
+				t.Fatalf(`expected %d attempts, got %d`, tt.wantAttempts, db.attempts)
+			}
+			if want := tt.wantCode == ""; want != (len(db.committed) == 1) {
+				t.Fatalf(`committed %v, want a commit %v`, db.committed, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+)
+
+type WebhookPostgresRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewWebhookPostgresRepository(conn *pgxpool.Pool) *WebhookPostgresRepository {
+	return &WebhookPostgresRepository{
+		conn: conn,
+	}
+}
+
+// webhookColumns lists columns read by scanWebhook.
+const webhookColumns = "id, url, events, secret, active, failures, created_at, updated_at"
+
+func scanWebhook(row pgx.Row, w *webhook.Webhook) error {
+	return row.Scan(&w.ID, &w.URL, &w.Events, &w.Secret, &w.Active, &w.Failures, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (r *WebhookPostgresRepository) Add(ctx context.Context, w *webhook.Webhook) error {
+	query := "INSERT INTO webhooks (url, events, secret, active) " +
+		"VALUES ($1, $2, $3, $4) RETURNING " + webhookColumns
+
+	err := scanWebhook(connFrom(ctx, r.conn).QueryRow(ctx, query, w.URL, w.Events, w.Secret, w.Active), w)
+	if err != nil {
+		return fmt.Errorf("query exec: %w", translateError(err))
+	}
+
+	return nil
+}
+
+func (r *WebhookPostgresRepository) Get(ctx context.Context, id int) (webhook.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE id = $1"
+
+	var w webhook.Webhook
+	err := scanWebhook(connFrom(ctx, r.conn).QueryRow(ctx, query, id), &w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return webhook.Webhook{}, webhook.ErrNotFound
+	}
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	return w, nil
+}
+
+func (r *WebhookPostgresRepository) List(ctx context.Context) ([]webhook.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks ORDER BY id"
+
+	rows, err := connFrom(ctx, r.conn).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var res []webhook.Webhook
+	for rows.Next() {
+		var w webhook.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		res = append(res, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *WebhookPostgresRepository) Update(ctx context.Context, w *webhook.Webhook) error {
+	query := "UPDATE webhooks SET url = $2, events = $3, secret = $4, active = $5, " +
+		"failures = CASE WHEN $5 AND NOT active THEN 0 ELSE failures END, updated_at = now() " +
+		"WHERE id = $1 RETURNING " + webhookColumns
+
+	err := scanWebhook(connFrom(ctx, r.conn).QueryRow(ctx, query, w.ID, w.URL, w.Events, w.Secret, w.Active), w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return webhook.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("query exec: %w", translateError(err))
+	}
+
+	return nil
+}
+
+func (r *WebhookPostgresRepository) Delete(ctx context.Context, id int) error {
+	query := "DELETE FROM webhooks WHERE id = $1"
+
+	tag, err := connFrom(ctx, r.conn).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return webhook.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookPostgresRepository) Deliveries(ctx context.Context, webhookID, limit int) ([]webhook.Delivery, error) {
+	query := "SELECT id, webhook_id, event_id, event_type, attempt, status_code, error, duration_ms, created_at " +
+		"FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2"
+
+	rows, err := connFrom(ctx, r.conn).Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var res []webhook.Delivery
+	for rows.Next() {
+		var (
+			d  webhook.Delivery
+			ms int64
+		)
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Attempt, &d.StatusCode, &d.Error, &ms, &d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		d.Duration = time.Duration(ms) * time.Millisecond
+		res = append(res, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *WebhookPostgresRepository) Enqueue(ctx context.Context, eventID int64, eventType string, payload []byte) error {
+	query := "INSERT INTO webhook_jobs (webhook_id, event_id, event_type, payload) " +
+		"SELECT id, $1, $2, $3 FROM webhooks WHERE active AND $2 = ANY (events) " +
+		"ON CONFLICT (webhook_id, event_id) DO NOTHING"
+
+	if _, err := connFrom(ctx, r.conn).Exec(ctx, query, eventID, eventType, payload); err != nil {
+		return fmt.Errorf("query exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookPostgresRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]webhook.Job, error) {
+	query := "UPDATE webhook_jobs j SET attempts = j.attempts + 1, " +
+		"next_attempt_at = now() + $2 * interval '1 millisecond' " +
+		"FROM webhooks w WHERE w.id = j.webhook_id AND j.id IN (" +
+		"SELECT jj.id FROM webhook_jobs jj JOIN webhooks ww ON ww.id = jj.webhook_id " +
+		"WHERE jj.finished_at IS NULL AND jj.next_attempt_at <= now() AND ww.active " +
+		"ORDER BY jj.id LIMIT $1 FOR UPDATE OF jj SKIP LOCKED) " +
+		"RETURNING j.id, j.event_id, j.event_type, j.payload, j.attempts, w.id, w.url, w.secret"
+
+	rows, err := connFrom(ctx, r.conn).Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var res []webhook.Job
+	for rows.Next() {
+		var j webhook.Job
+		err := rows.Scan(&j.ID, &j.EventID, &j.EventType, &j.Payload, &j.Attempt, &j.Webhook.ID, &j.Webhook.URL, &j.Webhook.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		res = append(res, j)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *WebhookPostgresRepository) Complete(ctx context.Context, job webhook.Job, d webhook.Delivery) error {
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := insertDelivery(ctx, tx, d); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE webhook_jobs SET finished_at = now() WHERE id = $1", job.ID); err != nil {
+			return fmt.Errorf("query exec: %w", err)
+		}
+
+		query := "UPDATE webhooks SET failures = 0 WHERE id = $1 AND failures <> 0"
+		if _, err := tx.Exec(ctx, query, job.Webhook.ID); err != nil {
+			return fmt.Errorf("query exec: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("complete tx: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookPostgresRepository) Fail(ctx context.Context, job webhook.Job, d webhook.Delivery, retryAt *time.Time, disableAfter int) error {
+	err := connFrom(ctx, r.conn).BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := insertDelivery(ctx, tx, d); err != nil {
+			return err
+		}
+
+		query := "UPDATE webhook_jobs SET finished_at = now() WHERE id = $1"
+		args := []interface{}{job.ID}
+		if retryAt != nil {
+			query = "UPDATE webhook_jobs SET next_attempt_at = $2 WHERE id = $1"
+			args = append(args, *retryAt)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("query exec: %w", err)
+		}
+
+		query = "UPDATE webhooks SET failures = failures + 1, " +
+			"active = active AND ($2 <= 0 OR failures + 1 < $2), updated_at = now() WHERE id = $1"
+		if _, err := tx.Exec(ctx, query, job.Webhook.ID, disableAfter); err != nil {
+			return fmt.Errorf("query exec: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail tx: %w", err)
+	}
+
+	return nil
+}
+
+func insertDelivery(ctx context.Context, tx pgx.Tx, d webhook.Delivery) error {
+	query := "INSERT INTO webhook_deliveries " +
+		"(webhook_id, event_id, event_type, attempt, status_code, error, duration_ms) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	_, err := tx.Exec(ctx, query,
+		d.WebhookID, d.EventID, d.EventType, d.Attempt, d.StatusCode, d.Error, d.Duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("insert delivery: %w", err)
+	}
+
+	return nil
+}
@@ -1,4 +1,183 @@
+// Package ipapico implements a geolocation client for the ipapi.co service.
 package ipapico
 
-// TODO
-// not implemented
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBaseURL  = "https://ipapi.co"
+	defaultTimeout  = 3 * time.Second
+	defaultCacheTTL = time.Hour
+	maxCacheEntries = 10000
+)
+
+// Package errors.
+var (
+	ErrInvalidIP   = errors.New("invalid ip address")
+	ErrReservedIP  = errors.New("reserved ip address")
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// APIError is returned when ipapi.co rejects the lookup.
+type APIError struct {
+	StatusCode int
+	Reason     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ipapi.co error, status %d: %s", e.StatusCode, e.Reason)
+}
+
+type Config struct {
+	// BaseURL of the service, defaults to https://ipapi.co.
+	BaseURL string
+	// Timeout of a single lookup, defaults to 3s.
+	Timeout time.Duration
+	// CacheTTL is how long resolved countries are cached, defaults to 1h.
+	CacheTTL time.Duration
+}
+
+// Client resolves IP addresses to countries.
+// It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	ttl        time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	country string
+	expires time.Time
+}
+
+func New(conf Config) *Client {
+	if conf.BaseURL == "" {
+		conf.BaseURL = defaultBaseURL
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultTimeout
+	}
+	if conf.CacheTTL <= 0 {
+		conf.CacheTTL = defaultCacheTTL
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(conf.BaseURL, "/"),
+		httpClient: &http.Client{Timeout: conf.Timeout},
+		ttl:        conf.CacheTTL,
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+type lookupResponse struct {
+	CountryCode string `json:"country_code"`
+	Error       bool   `json:"error"`
+	Reason      string `json:"reason"`
+	Reserved    bool   `json:"reserved"`
+}
+
+// Country returns the ISO 3166-1 alpha-2 country code of the IP address.
+func (c *Client) Country(ctx context.Context, ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", ErrInvalidIP
+	}
+	ip = parsed.String()
+
+	if country, ok := c.cached(ip); ok {
+		return country, nil
+	}
+
+	country, err := c.lookup(ctx, ip)
+	if err != nil {
+		return "", err
+	}
+
+	c.store(ip, country)
+
+	return country, nil
+}
+
+func (c *Client) lookup(ctx context.Context, ip string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/"+url.PathEscape(ip)+"/json/", nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", ErrRateLimited
+	}
+
+	var body lookupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", &APIError{StatusCode: resp.StatusCode, Reason: http.StatusText(resp.StatusCode)}
+		}
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+
+	switch {
+	case body.Reserved:
+		return "", ErrReservedIP
+	case body.Error || resp.StatusCode != http.StatusOK:
+		return "", &APIError{StatusCode: resp.StatusCode, Reason: body.Reason}
+	case body.CountryCode == "":
+		return "", &APIError{StatusCode: resp.StatusCode, Reason: "empty country code"}
+	}
+
+	return body.CountryCode, nil
+}
+
+func (c *Client) cached(ip string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.cache[ip]
+	if !ok || time.Now().After(e.expires) {
+		return "", false
+	}
+
+	return e.country, true
+}
+
+func (c *Client) store(ip, country string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.cache) >= maxCacheEntries {
+		now := time.Now()
+		for k, e := range c.cache {
+			if now.After(e.expires) {
+				delete(c.cache, k)
+			}
+		}
+	}
+	if len(c.cache) >= maxCacheEntries {
+		for k := range c.cache {
+			delete(c.cache, k)
+			break
+		}
+	}
+
+	c.cache[ip] = cacheEntry{country: country, expires: time.Now().Add(c.ttl)}
+}
@@ -0,0 +1,54 @@
+package ipapico_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/ipapico"
+)
+
+func TestClientCountry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		switch r.URL.Path {
+		case "/8.8.8.8/json/":
+			_, _ = w.Write([]byte(`{"ip":"8.8.8.8","country_code":"US"}`))
+		case "/10.0.0.1/json/":
+			_, _ = w.Write([]byte(`{"ip":"10.0.0.1","error":true,"reason":"Reserved IP Address","reserved":true}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	c := ipapico.New(ipapico.Config{BaseURL: srv.URL})
+
+	for i := 0; i < 2; i++ {
+		country, err := c.Country(context.Background(), "8.8.8.8")
+		if err != nil {
+			t.Fatalf(`unexpected error %v`, err)
+		}
+		if country != "US" {
+			t.Fatalf(`expected country %q, got %q`, "US", country)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf(`expected a single cached lookup, got %d`, n)
+	}
+
+	if _, err := c.Country(context.Background(), "10.0.0.1"); !errors.Is(err, ipapico.ErrReservedIP) {
+		t.Fatalf(`expected error %v, got %v`, ipapico.ErrReservedIP, err)
+	}
+	if _, err := c.Country(context.Background(), "1.1.1.1"); !errors.Is(err, ipapico.ErrRateLimited) {
+		t.Fatalf(`expected error %v, got %v`, ipapico.ErrRateLimited, err)
+	}
+	if _, err := c.Country(context.Background(), "not an ip"); !errors.Is(err, ipapico.ErrInvalidIP) {
+		t.Fatalf(`expected error %v, got %v`, ipapico.ErrInvalidIP, err)
+	}
+}
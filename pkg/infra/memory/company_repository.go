@@ -0,0 +1,560 @@
+// Package memory keeps companies in process memory,
+// for development and tests without a database.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/transaction"
+)
+
+// CompanyRepository stores companies in memory with the semantics
+// of the Postgres repository. It also implements transaction.Manager,
+// transactions take the repository over until they end, so they
+// are serializable. Nothing is published to the outbox.
+type CompanyRepository struct {
+	mu    sync.RWMutex
+	state state
+}
+
+// state is the data rolled back by a failed transaction.
+type state struct {
+	companies   map[int]company.Company
+	audit       []company.AuditRecord
+	lastID      int
+	lastAuditID int64
+}
+
+func (s state) clone() state {
+	res := s
+	res.companies = make(map[int]company.Company, len(s.companies))
+	for id, c := range s.companies {
+		res.companies[id] = c
+	}
+	res.audit = append([]company.AuditRecord(nil), s.audit...)
+
+	return res
+}
+
+func NewCompanyRepository() *CompanyRepository {
+	return &CompanyRepository{
+		state: state{companies: map[int]company.Company{}},
+	}
+}
+
+type txKey struct{}
+
+// inTx reports whether the context carries a transaction of the repository,
+// which already holds the lock.
+func (r *CompanyRepository) inTx(ctx context.Context) bool {
+	tx, _ := ctx.Value(txKey{}).(*CompanyRepository)
+	return tx == r
+}
+
+// rlock locks the repository for reading unless the context carries its transaction
+// and returns the unlock function.
+func (r *CompanyRepository) rlock(ctx context.Context) func() {
+	if r.inTx(ctx) {
+		return func() {}
+	}
+
+	r.mu.RLock()
+	return r.mu.RUnlock
+}
+
+// lock locks the repository for writing unless the context carries its transaction
+// and returns the unlock function.
+func (r *CompanyRepository) lock(ctx context.Context) func() {
+	if r.inTx(ctx) {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// Do runs fn holding the repository and restores its state if fn fails.
+// Nested calls restore only the changes made by themselves.
+func (r *CompanyRepository) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	unlock := r.lock(ctx)
+	defer unlock()
+
+	snapshot := r.state.clone()
+	if err := fn(context.WithValue(transaction.WithActive(ctx), txKey{}, r)); err != nil {
+		r.state = snapshot
+		return err
+	}
+
+	return nil
+}
+
+func (r *CompanyRepository) Add(ctx context.Context, raw *company.Company) error {
+	defer r.lock(ctx)()
+
+	if err := r.checkCode(raw.Code, 0); err != nil {
+		return err
+	}
+
+	r.state.lastID++
+	row := company.Company{
+		ID:      r.state.lastID,
+		Code:    raw.Code,
+		Name:    raw.Name,
+		Country: raw.Country,
+		Website: raw.Website,
+		Phone:   raw.Phone,
+		Version: 1,
+	}
+	r.state.companies[row.ID] = row
+	r.record(ctx, company.OpCreate, row.ID, nil, &row)
+
+	*raw = row
+
+	return nil
+}
+
+func (r *CompanyRepository) Get(ctx context.Context, id int, opts company.GetOptions) (company.Company, error) {
+	defer r.rlock(ctx)()
+
+	return r.get(id, 0, opts)
+}
+
+func (r *CompanyRepository) List(ctx context.Context, opts company.ListOptions) (company.Page, error) {
+	if err := opts.CheckCursor(); err != nil {
+		return company.Page{}, err
+	}
+
+	order := opts.Order()
+	if err := checkOrder(order); err != nil {
+		return company.Page{}, err
+	}
+
+	defer r.rlock(ctx)()
+
+	rows := r.filter(opts)
+	sortCompanies(rows, order, opts.Backward())
+
+	if opts.Cursor != nil {
+		n := sort.Search(len(rows), func(i int) bool {
+			return compareCursor(rows[i], order, opts.Cursor) > 0
+		})
+		rows = rows[n:]
+	}
+	if len(rows) > opts.PageSize()+1 {
+		rows = rows[:opts.PageSize()+1]
+	}
+
+	return opts.Paginate(append([]company.Company(nil), rows...)), nil
+}
+
+// Export passes a snapshot of the companies to fn,
+// so that fn may use the repository.
+func (r *CompanyRepository) Export(ctx context.Context, opts company.ListOptions, fn func(company.Company) error) error {
+	order := opts.Order()
+	if err := checkOrder(order); err != nil {
+		return err
+	}
+
+	unlock := r.rlock(ctx)
+	rows := r.filter(opts)
+	unlock()
+
+	sortCompanies(rows, order, false)
+
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *CompanyRepository) Count(ctx context.Context, opts company.ListOptions) (int, error) {
+	defer r.rlock(ctx)()
+
+	return len(r.filter(opts)), nil
+}
+
+// Update overwrites the company. If row.Version is not zero
+// the update is applied only if the stored version matches it.
+func (r *CompanyRepository) Update(ctx context.Context, row *company.Company) error {
+	defer r.lock(ctx)()
+
+	before, err := r.get(row.ID, row.Version, company.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	after := before
+	after.Code, after.Name, after.Country, after.Website, after.Phone =
+		row.Code, row.Name, row.Country, row.Website, row.Phone
+
+	if err := r.save(ctx, company.OpUpdate, before, &after); err != nil {
+		return err
+	}
+	*row = after
+
+	return nil
+}
+
+// Patch changes the fields set in the patch. If version is not zero
+// the patch is applied only if the stored version matches it.
+func (r *CompanyRepository) Patch(ctx context.Context, id, version int, patch company.Patch) (company.Company, error) {
+	defer r.lock(ctx)()
+
+	before, err := r.get(id, version, company.GetOptions{})
+	if err != nil || patch.Empty() {
+		return before, err
+	}
+
+	after := patch.Apply(before)
+	if err := r.save(ctx, company.OpPatch, before, &after); err != nil {
+		return company.Company{}, err
+	}
+
+	return after, nil
+}
+
+// Delete marks the company deleted. If version is not zero
+// the company is deleted only if the stored version matches it.
+func (r *CompanyRepository) Delete(ctx context.Context, id, version int) error {
+	defer r.lock(ctx)()
+
+	before, err := r.get(id, version, company.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	after := before
+	after.DeletedAt = &now
+	after.DeletedBy = company.ActorFrom(ctx).ID
+
+	after.Version++
+	r.state.companies[id] = after
+	r.record(ctx, company.OpDelete, id, &before, nil)
+
+	return nil
+}
+
+// Restore undeletes the company. If version is not zero
+// the company is restored only if the stored version matches it.
+func (r *CompanyRepository) Restore(ctx context.Context, id, version int) (company.Company, error) {
+	defer r.lock(ctx)()
+
+	before, err := r.get(id, version, company.GetOptions{IncludeDeleted: true})
+	if err != nil {
+		return company.Company{}, err
+	}
+	if !before.Deleted() {
+		return company.Company{}, &company.ConflictError{Detail: fmt.Sprintf("company %d is not deleted", id)}
+	}
+
+	// the code may have been taken by another company meanwhile
+	if err := r.checkCode(before.Code, id); err != nil {
+		return company.Company{}, err
+	}
+
+	after := before
+	after.DeletedAt, after.DeletedBy = nil, ""
+
+	after.Version++
+	r.state.companies[id] = after
+	r.record(ctx, company.OpRestore, id, &before, &after)
+
+	return after, nil
+}
+
+// Purge removes companies deleted before the time for good,
+// recording the purge in their audit trail on behalf of the actor of the context.
+func (r *CompanyRepository) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	defer r.lock(ctx)()
+
+	var ids []int
+	for id, c := range r.state.companies {
+		if c.Deleted() && c.DeletedAt.Before(deletedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	actor := company.ActorFrom(ctx)
+	for _, id := range ids {
+		delete(r.state.companies, id)
+		r.state.lastAuditID++
+		r.state.audit = append(r.state.audit, company.AuditRecord{
+			ID:        r.state.lastAuditID,
+			CompanyID: id,
+			Actor:     actor.ID,
+			Operation: company.OpPurge,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return len(ids), nil
+}
+
+// Import stores the accepted rows in one go. As with a database sequence,
+// IDs of the accepted rows of a rolled back atomic import are not reused.
+func (r *CompanyRepository) Import(ctx context.Context, source company.ImportSource, atomic bool) (company.ImportReport, error) {
+	defer r.lock(ctx)()
+
+	var (
+		results []company.ImportResult
+		rows    = map[int]company.Company{}
+		seen    = map[string]bool{}
+	)
+	for source.Next() {
+		row := source.Row()
+		if row.Err != nil {
+			results = append(results, company.ImportResult{Line: row.Line, Status: company.ImportRejected, Err: row.Err})
+			continue
+		}
+
+		// the first row of a code not taken by a live company wins
+		c := row.Company
+		if c.Code != "" {
+			taken := seen[c.Code] || r.checkCode(c.Code, 0) != nil
+			seen[c.Code] = true
+			if taken {
+				results = append(results, company.ImportResult{Line: row.Line, Status: company.ImportDuplicate})
+				continue
+			}
+		}
+
+		r.state.lastID++
+		rows[row.Line] = company.Company{
+			ID:      r.state.lastID,
+			Code:    c.Code,
+			Name:    c.Name,
+			Country: c.Country,
+			Website: c.Website,
+			Phone:   c.Phone,
+			Version: 1,
+		}
+		results = append(results, company.ImportResult{Line: row.Line, Status: company.ImportAccepted, ID: r.state.lastID})
+	}
+	if err := source.Err(); err != nil {
+		return company.ImportReport{}, fmt.Errorf("read rows: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+
+	var report company.ImportReport
+	for _, res := range results {
+		report.Add(res)
+	}
+	if atomic && report.Accepted != len(report.Rows) {
+		return report, nil
+	}
+
+	for _, res := range report.Rows {
+		if res.Status != company.ImportAccepted {
+			continue
+		}
+		row := rows[res.Line]
+		r.state.companies[row.ID] = row
+		r.record(ctx, company.OpImport, row.ID, nil, &row)
+	}
+	report.Committed = true
+
+	return report, nil
+}
+
+func (r *CompanyRepository) History(ctx context.Context, id int, opts company.HistoryOptions) (company.HistoryPage, error) {
+	defer r.rlock(ctx)()
+
+	var page company.HistoryPage
+	for i := len(r.state.audit) - 1; i >= 0; i-- {
+		rec := r.state.audit[i]
+
+		switch {
+		case rec.CompanyID != id,
+			opts.Actor != "" && rec.Actor != opts.Actor,
+			opts.Since != nil && rec.CreatedAt.Before(*opts.Since),
+			opts.Until != nil && rec.CreatedAt.After(*opts.Until),
+			opts.Cursor > 0 && rec.ID >= opts.Cursor:
+			continue
+		}
+
+		if len(page.Items) == opts.PageSize() {
+			page.Next = page.Items[len(page.Items)-1].ID
+			break
+		}
+		page.Items = append(page.Items, rec)
+	}
+
+	return page, nil
+}
+
+// get returns the stored company. If version is not zero it must match the stored one.
+func (r *CompanyRepository) get(id, version int, opts company.GetOptions) (company.Company, error) {
+	row, ok := r.state.companies[id]
+	if !ok || (row.Deleted() && !opts.IncludeDeleted) {
+		return company.Company{}, &company.NotFoundError{ID: id}
+	}
+
+	if version != 0 && row.Version != version {
+		return company.Company{}, &company.PreconditionFailedError{
+			Detail: fmt.Sprintf("company %d is at version %d, expected %d", id, row.Version, version),
+		}
+	}
+
+	return row, nil
+}
+
+// save stores the changed company bumping its version.
+func (r *CompanyRepository) save(ctx context.Context, op string, before company.Company, after *company.Company) error {
+	if err := r.checkCode(after.Code, after.ID); err != nil {
+		return err
+	}
+
+	after.Version++
+	r.state.companies[after.ID] = *after
+	r.record(ctx, op, after.ID, &before, after)
+
+	return nil
+}
+
+// checkCode returns a *company.ConflictError if a live company
+// other than the one with the ID has the code. Empty codes never clash.
+func (r *CompanyRepository) checkCode(code string, id int) error {
+	if code == "" {
+		return nil
+	}
+
+	for _, c := range r.state.companies {
+		if c.ID != id && c.Code == code && !c.Deleted() {
+			return &company.ConflictError{Detail: fmt.Sprintf("company already exists: code %q is taken", code)}
+		}
+	}
+
+	return nil
+}
+
+// record appends the change made by the actor of the context to the audit trail.
+func (r *CompanyRepository) record(ctx context.Context, op string, id int, before, after *company.Company) {
+	actor := company.ActorFrom(ctx)
+
+	r.state.lastAuditID++
+	r.state.audit = append(r.state.audit, company.AuditRecord{
+		ID:        r.state.lastAuditID,
+		CompanyID: id,
+		Actor:     actor.ID,
+		RequestID: actor.RequestID,
+		Operation: op,
+		Changes:   company.Diff(before, after),
+		CreatedAt: time.Now(),
+	})
+}
+
+// filter returns the companies matching the filters of the options in no particular order.
+func (r *CompanyRepository) filter(opts company.ListOptions) []company.Company {
+	name := strings.ToLower(opts.Name)
+
+	var res []company.Company
+	for _, c := range r.state.companies {
+		switch {
+		case name != "" && !strings.Contains(strings.ToLower(c.Name), name),
+			opts.Code != "" && c.Code != opts.Code,
+			opts.Country != "" && c.Country != opts.Country,
+			opts.Website != "" && c.Website != opts.Website,
+			opts.Phone != "" && c.Phone != opts.Phone,
+			c.Deleted() && !opts.IncludeDeleted:
+			continue
+		}
+		res = append(res, c)
+	}
+
+	return res
+}
+
+// checkOrder rejects fields the companies cannot be sorted by.
+func checkOrder(order []company.Sort) error {
+	for _, s := range order {
+		switch s.Field {
+		case company.SortByID, company.SortByCode, company.SortByName,
+			company.SortByCountry, company.SortByWebsite, company.SortByPhone:
+		default:
+			return &company.ValidationError{
+				Detail: fmt.Sprintf("cannot sort by %q", s.Field),
+				Err:    company.ErrInvalidSort,
+			}
+		}
+	}
+
+	return nil
+}
+
+// compareField compares a field of two companies, strings by their bytes.
+func compareField(f company.SortField, a, b company.Company) int {
+	if f == company.SortByID {
+		return compareInts(a.ID, b.ID)
+	}
+
+	return strings.Compare(f.Value(a), f.Value(b))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sortCompanies sorts the companies in the order, reversed when walking backward.
+func sortCompanies(rows []company.Company, order []company.Sort, backward bool) {
+	sort.Slice(rows, func(i, j int) bool {
+		for _, s := range order {
+			c := compareField(s.Field, rows[i], rows[j])
+			if s.Desc != backward {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+
+		return false
+	})
+}
+
+// compareCursor compares the company with the position of the cursor
+// in the walk direction, it is positive for companies after the cursor.
+// The order ends with id, the cursor keys hold the fields preceding it.
+func compareCursor(c company.Company, order []company.Sort, cursor *company.Cursor) int {
+	for i, s := range order {
+		var res int
+		if i < len(cursor.Keys) {
+			res = strings.Compare(s.Field.Value(c), cursor.Keys[i])
+		} else {
+			res = compareInts(c.ID, cursor.ID)
+		}
+
+		if s.Desc != cursor.Backward {
+			res = -res
+		}
+		if res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
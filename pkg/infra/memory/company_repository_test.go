@@ -0,0 +1,276 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/memory"
+)
+
+func newRepo(t *testing.T, fixtures string) *memory.CompanyRepository {
+	t.Helper()
+
+	repo := memory.NewCompanyRepository()
+	if err := repo.LoadFixtures(context.Background(), strings.NewReader(fixtures)); err != nil {
+		t.Fatalf(`LoadFixtures() error = %v`, err)
+	}
+
+	return repo
+}
+
+const listFixtures = `[
+	{"code": "C1", "name": "Beta", "country": "US"},
+	{"code": "C2", "name": "alpha", "country": "DE"},
+	{"code": "C3", "name": "Gamma", "country": "US"},
+	{"code": "C4", "name": "Alphabet", "country": "US"},
+	{"code": "C5", "name": "Delta", "country": "DE"}
+]`
+
+func ids(items []company.Company) []int {
+	res := make([]int, 0, len(items))
+	for _, c := range items {
+		res = append(res, c.ID)
+	}
+
+	return res
+}
+
+func TestCompanyRepositoryList(t *testing.T) {
+	tests := []struct {
+		name string
+		opts company.ListOptions
+		want []int
+	}{
+		{
+			name: "id order",
+			want: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name: "name filter is case-insensitive",
+			opts: company.ListOptions{Name: "ALPHA"},
+			want: []int{2, 4},
+		},
+		{
+			name: "exact filter",
+			opts: company.ListOptions{Country: "DE"},
+			want: []int{2, 5},
+		},
+		{
+			name: "sort by country desc and name",
+			opts: company.ListOptions{Sort: []company.Sort{
+				{Field: company.SortByCountry, Desc: true},
+				{Field: company.SortByName},
+			}},
+			want: []int{4, 1, 3, 5, 2},
+		},
+	}
+
+	repo := newRepo(t, listFixtures)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf(`List() error = %v`, err)
+			}
+			if got := ids(page.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf(`List() = %v, want %v`, got, tt.want)
+			}
+
+			count, err := repo.Count(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf(`Count() error = %v`, err)
+			}
+			if count != len(tt.want) {
+				t.Fatalf(`Count() = %d, want %d`, count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCompanyRepositoryListPagination(t *testing.T) {
+	repo := newRepo(t, listFixtures)
+	ctx := context.Background()
+	opts := company.ListOptions{Sort: []company.Sort{{Field: company.SortByName}}, Limit: 2}
+
+	var (
+		got    [][]int
+		cursor *company.Cursor
+	)
+	for {
+		opts.Cursor = cursor
+		page, err := repo.List(ctx, opts)
+		if err != nil {
+			t.Fatalf(`List() error = %v`, err)
+		}
+		got = append(got, ids(page.Items))
+
+		if page.Next == nil {
+			break
+		}
+		cursor = page.Next
+	}
+
+	want := [][]int{{4, 1}, {5, 3}, {2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`pages = %v, want %v`, got, want)
+	}
+
+	// walk back from the last page
+	opts.Cursor = cursor
+	last, err := repo.List(ctx, opts)
+	if err != nil {
+		t.Fatalf(`List() error = %v`, err)
+	}
+	opts.Cursor = last.Prev
+	prev, err := repo.List(ctx, opts)
+	if err != nil {
+		t.Fatalf(`List() error = %v`, err)
+	}
+	if got := ids(prev.Items); !reflect.DeepEqual(got, []int{5, 3}) {
+		t.Fatalf(`previous page = %v, want [5 3]`, got)
+	}
+	if prev.Prev == nil || prev.Next == nil {
+		t.Fatalf(`previous page cursors = %v, %v, want both`, prev.Prev, prev.Next)
+	}
+
+	opts.Sort = nil
+	if _, err := repo.List(ctx, opts); !errors.Is(err, company.ErrInvalidCursor) {
+		t.Fatalf(`List() with a cursor of another ordering error = %v, want ErrInvalidCursor`, err)
+	}
+}
+
+func TestCompanyRepositoryWrites(t *testing.T) {
+	repo := newRepo(t, `[{"code": "C1", "name": "Beta"}]`)
+	ctx := company.WithActor(context.Background(), company.Actor{ID: "alice"})
+
+	var conflict *company.ConflictError
+	if err := repo.Add(ctx, &company.Company{Code: "C1", Name: "Other"}); !errors.As(err, &conflict) {
+		t.Fatalf(`Add() with a taken code error = %v, want *ConflictError`, err)
+	}
+
+	row := company.Company{ID: 1, Code: "C1", Name: "Beta Inc", Version: 2}
+	var precondition *company.PreconditionFailedError
+	if err := repo.Update(ctx, &row); !errors.As(err, &precondition) {
+		t.Fatalf(`Update() at a stale version error = %v, want *PreconditionFailedError`, err)
+	}
+
+	row.Version = 1
+	if err := repo.Update(ctx, &row); err != nil {
+		t.Fatalf(`Update() error = %v`, err)
+	}
+	if row.Version != 2 {
+		t.Fatalf(`Update() version = %d, want 2`, row.Version)
+	}
+
+	if err := repo.Delete(ctx, 1, 2); err != nil {
+		t.Fatalf(`Delete() error = %v`, err)
+	}
+	var notFound *company.NotFoundError
+	if _, err := repo.Get(ctx, 1, company.GetOptions{}); !errors.As(err, &notFound) {
+		t.Fatalf(`Get() of a deleted company error = %v, want *NotFoundError`, err)
+	}
+	deleted, err := repo.Get(ctx, 1, company.GetOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf(`Get() with deleted error = %v`, err)
+	}
+	if !deleted.Deleted() || deleted.DeletedBy != "alice" || deleted.Version != 3 {
+		t.Fatalf(`Get() = %+v, want deleted by alice at version 3`, deleted)
+	}
+
+	// the code of a deleted company is free
+	if err := repo.Add(ctx, &company.Company{Code: "C1", Name: "Other"}); err != nil {
+		t.Fatalf(`Add() error = %v`, err)
+	}
+	if _, err := repo.Restore(ctx, 1, 0); !errors.As(err, &conflict) {
+		t.Fatalf(`Restore() with a taken code error = %v, want *ConflictError`, err)
+	}
+	if err := repo.Delete(ctx, 2, 0); err != nil {
+		t.Fatalf(`Delete() error = %v`, err)
+	}
+	if _, err := repo.Restore(ctx, 1, 0); err != nil {
+		t.Fatalf(`Restore() error = %v`, err)
+	}
+
+	history, err := repo.History(ctx, 1, company.HistoryOptions{})
+	if err != nil {
+		t.Fatalf(`History() error = %v`, err)
+	}
+	var ops []string
+	for _, rec := range history.Items {
+		ops = append(ops, rec.Operation)
+	}
+	if want := []string{company.OpRestore, company.OpDelete, company.OpUpdate, company.OpCreate}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf(`History() operations = %v, want %v`, ops, want)
+	}
+	// the restored company is compared with the deleted one, not recreated
+	if changes := history.Items[0].Changes; len(changes) != 0 {
+		t.Fatalf(`History() restore changes = %+v, want none`, changes)
+	}
+}
+
+func TestCompanyRepositoryDo(t *testing.T) {
+	repo := newRepo(t, `[{"code": "C1", "name": "Beta"}]`)
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	err := repo.Do(ctx, func(ctx context.Context) error {
+		if err := repo.Add(ctx, &company.Company{Code: "C2", Name: "Gamma"}); err != nil {
+			return err
+		}
+
+		// a failed nested transaction rolls back its own changes only
+		err := repo.Do(ctx, func(ctx context.Context) error {
+			if _, err := repo.Patch(ctx, 1, 0, company.Patch{Name: new(string)}); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Fatalf(`nested Do() error = %v, want %v`, err, errFailed)
+		}
+
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf(`Do() error = %v, want %v`, err, errFailed)
+	}
+
+	page, err := repo.List(ctx, company.ListOptions{})
+	if err != nil {
+		t.Fatalf(`List() error = %v`, err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "Beta" || page.Items[0].Version != 1 {
+		t.Fatalf(`List() after rollback = %+v, want the fixture only`, page.Items)
+	}
+}
+
+func TestCompanyRepositorySearch(t *testing.T) {
+	repo := newRepo(t, `[
+		{"code": "ACME", "name": "Acme Widgets"},
+		{"code": "GLOBEX", "name": "Globex Corporation"},
+		{"code": "INITECH", "name": "Initech"}
+	]`)
+
+	page, err := repo.Search(context.Background(), company.SearchOptions{Query: "wid acm"})
+	if err != nil {
+		t.Fatalf(`Search() error = %v`, err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Company.ID != 1 {
+		t.Fatalf(`Search() = %+v, want Acme`, page.Items)
+	}
+	if want := "<mark>Acme</mark> <mark>Widgets</mark>"; page.Items[0].Highlight != want {
+		t.Fatalf(`Search() highlight = %q, want %q`, page.Items[0].Highlight, want)
+	}
+
+	page, err = repo.Search(context.Background(), company.SearchOptions{Query: "globx"})
+	if err != nil {
+		t.Fatalf(`Search() error = %v`, err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Company.ID != 2 {
+		t.Fatalf(`Search() of a misspelled name = %+v, want Globex`, page.Items)
+	}
+}
@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// fixture is a company of a fixtures file.
+type fixture struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Country string `json:"country"`
+	Website string `json:"website"`
+	Phone   string `json:"phone"`
+}
+
+// LoadFixtures adds the companies of a JSON array read from rd, e.g.
+//
+//	[{"code": "ACME", "name": "Acme", "country": "US"}]
+//
+// Companies are added as they are, without the validation of the service.
+func (r *CompanyRepository) LoadFixtures(ctx context.Context, rd io.Reader) error {
+	var fixtures []fixture
+	if err := json.NewDecoder(rd).Decode(&fixtures); err != nil {
+		return fmt.Errorf("decode fixtures: %w", err)
+	}
+
+	return r.Do(ctx, func(ctx context.Context) error {
+		for i, f := range fixtures {
+			c := company.Company{Code: f.Code, Name: f.Name, Country: f.Country, Website: f.Website, Phone: f.Phone}
+			if err := r.Add(ctx, &c); err != nil {
+				return fmt.Errorf("add fixture %d: %w", i, err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/company"
+)
+
+// searchSimilarity is the word similarity threshold of misspelled names.
+const searchSimilarity = 0.3
+
+// Search matches the query words as prefixes of the words of names and codes,
+// along with names similar to the query. Hits are ranked by the share of the
+// query words matched plus the word similarity of the name, which mirrors
+// the ranking of the Postgres repository without reproducing its exact values.
+func (r *CompanyRepository) Search(ctx context.Context, opts company.SearchOptions) (company.SearchPage, error) {
+	rank, id, err := opts.CheckCursor()
+	if err != nil {
+		return company.SearchPage{}, err
+	}
+
+	words := splitWords(opts.Query)
+	query := trigrams(opts.Query)
+
+	unlock := r.rlock(ctx)
+	var hits []company.SearchHit
+	for _, c := range r.state.companies {
+		if c.Deleted() || (opts.Country != "" && c.Country != opts.Country) {
+			continue
+		}
+
+		matched := prefixMatches(words, append(splitWords(c.Name), splitWords(c.Code)...))
+		similarity := wordSimilarity(query, trigrams(c.Name))
+		if (len(words) == 0 || matched < len(words)) && similarity < searchSimilarity {
+			continue
+		}
+
+		var textRank float64
+		if len(words) > 0 {
+			textRank = float64(matched) / float64(len(words))
+		}
+		hits = append(hits, company.SearchHit{
+			Company:   c,
+			Rank:      textRank + similarity,
+			Highlight: highlight(c.Name, words),
+		})
+	}
+	unlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Company.ID < hits[j].Company.ID
+	})
+
+	if opts.Cursor != nil {
+		n := sort.Search(len(hits), func(i int) bool {
+			h := hits[i]
+			return h.Rank < rank || (h.Rank == rank && h.Company.ID > id)
+		})
+		hits = hits[n:]
+	}
+	if len(hits) > opts.PageSize()+1 {
+		hits = hits[:opts.PageSize()+1]
+	}
+
+	return opts.Paginate(hits), nil
+}
+
+// splitWords returns the lowercase words of the text,
+// other characters separate the words.
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixMatches counts the query words that start any of the words.
+func prefixMatches(query, words []string) int {
+	var n int
+	for _, q := range query {
+		for _, w := range words {
+			if strings.HasPrefix(w, q) {
+				n++
+				break
+			}
+		}
+	}
+
+	return n
+}
+
+// trigrams returns the trigrams of the words of the text the way pg_trgm does,
+// each word padded with two spaces in front and one behind.
+func trigrams(s string) map[string]bool {
+	res := map[string]bool{}
+	for _, w := range splitWords(s) {
+		runes := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			res[string(runes[i:i+3])] = true
+		}
+	}
+
+	return res
+}
+
+// wordSimilarity is the share of the query trigrams found in the text,
+// a simpler take on word_similarity of pg_trgm.
+func wordSimilarity(query, text map[string]bool) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+
+	var n int
+	for t := range query {
+		if text[t] {
+			n++
+		}
+	}
+
+	return float64(n) / float64(len(query))
+}
+
+// highlight wraps the words of the name starting with a query word
+// in the highlight markers, leaving the rest of the name as is.
+func highlight(name string, query []string) string {
+	var (
+		b     strings.Builder
+		start = -1
+	)
+	flush := func(end int) {
+		word := name[start:end]
+		if prefixMatches(query, []string{strings.ToLower(word)}) > 0 {
+			b.WriteString(company.HighlightStart + word + company.HighlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		start = -1
+	}
+
+	for i, r := range name {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			flush(i)
+		}
+		if !inWord {
+			b.WriteRune(r)
+		}
+	}
+	if start >= 0 {
+		flush(len(name))
+	}
+
+	return b.String()
+}
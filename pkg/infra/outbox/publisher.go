@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+// LogPublisher writes events to the log.
+type LogPublisher struct {
+	Logger log.Interface
+}
+
+func (p LogPublisher) Publish(_ context.Context, e Event) error {
+	p.Logger.Info("event",
+		"id", e.ID,
+		"type", e.Type,
+		"version", e.Version,
+		"aggregateId", e.AggregateID,
+		"payload", string(e.Payload),
+	)
+
+	return nil
+}
+
+// HTTPPublisher POSTs every event as JSON to the URL.
+// Any non-2xx response is a delivery failure.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPPublisher(url string, timeout time.Duration) *HTTPPublisher {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &HTTPPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(e.ID, 10))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
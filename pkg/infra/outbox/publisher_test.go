@@ -0,0 +1,43 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/outbox"
+)
+
+func TestHTTPPublisher(t *testing.T) {
+	var got outbox.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf(`decode event: %v`, err)
+		}
+		if key := r.Header.Get("Idempotency-Key"); key != "7" {
+			t.Errorf(`expected idempotency key %q, got %q`, "7", key)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p := outbox.NewHTTPPublisher(srv.URL, 0)
+
+	event := outbox.Event{ID: 7, Type: "company.created", Version: 1, AggregateID: 3, Payload: json.RawMessage(`{"before":null}`)}
+	if err := p.Publish(context.Background(), event); err != nil {
+		t.Fatalf(`unexpected error %v`, err)
+	}
+	if got.ID != 7 || got.Type != "company.created" || got.AggregateID != 3 {
+		t.Fatalf(`unexpected event %+v`, got)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	failing := outbox.NewHTTPPublisher(missing.URL, 0)
+	if err := failing.Publish(context.Background(), event); err == nil {
+		t.Fatalf(`expected error for non-2xx response`)
+	}
+}
@@ -0,0 +1,251 @@
+// Package outbox relays change events written to the transactional outbox
+// to downstream consumers.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+const (
+	defaultInterval  = time.Second
+	defaultBatchSize = 100
+	defaultRetention = 24 * time.Hour
+
+	// relayLockID is the advisory lock held by the relay draining
+	// the outbox, "outbox" in ASCII.
+	relayLockID = 0x6f7574626f78
+)
+
+// Event is a versioned change event as handed to publishers.
+type Event struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Version     int             `json:"version"`
+	AggregateID int             `json:"aggregateId"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// Publisher delivers events downstream.
+// Publish must be idempotent for the consumers: delivery is at least once,
+// an event is delivered again if the relay stops before marking it published.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+type Config struct {
+	// Interval between polls when the outbox is drained, defaults to 1s.
+	Interval time.Duration
+	// BatchSize is the number of events locked per transaction, defaults to 100.
+	BatchSize int
+	// Retention is how long published events are kept, defaults to 24h.
+	Retention time.Duration
+}
+
+// relayConn is the part of *pgxpool.Pool used by the relay.
+type relayConn interface {
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+}
+
+// Relay drains the outbox with at least once delivery, publishing the events
+// of a company in the order their changes were committed. Changes lock the
+// company row before writing their event, so the ids of the events of
+// a company follow the commits, and events are published in id order,
+// stopping at the first failure. Relays of several instances take turns
+// with an advisory lock, a single one drains the outbox at a time.
+// Events of different companies may be published out of commit order.
+// Published events are removed once they are older than the retention.
+//
+// Every event is published to each of the publishers in turn. When one of
+// them fails, the retries of the event skip the publishers that took it,
+// as long as the relay runs.
+type Relay struct {
+	conn       relayConn
+	publishers []Publisher
+	logger     log.Interface
+	conf       Config
+
+	mu sync.Mutex
+	// delivered holds the publishers that took an event failed on a later one.
+	delivered map[int64][]bool
+}
+
+func NewRelay(conn *pgxpool.Pool, publishers []Publisher, logger log.Interface, conf Config) *Relay {
+	if conf.Interval <= 0 {
+		conf.Interval = defaultInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaultBatchSize
+	}
+	if conf.Retention <= 0 {
+		conf.Retention = defaultRetention
+	}
+
+	return &Relay{
+		conn:       conn,
+		publishers: publishers,
+		logger:     logger,
+		conf:       conf,
+		delivered:  map[int64][]bool{},
+	}
+}
+
+// Run relays events until the context is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		// drain full batches without waiting for the next tick
+		for {
+			n, err := r.RelayBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.logger.Error(err, "relay outbox batch")
+			}
+			if err != nil || n < r.conf.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes a single batch of pending events and returns
+// the number of events published. Publishing stops at the first failure,
+// the failed event and the ones after it are retried with the next batch.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var published int
+
+	err := r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", relayLockID).Scan(&locked); err != nil {
+			return fmt.Errorf("lock relay: %w", err)
+		}
+		if !locked {
+			// another instance is draining the outbox
+			return nil
+		}
+
+		events, err := pendingEvents(ctx, tx, r.conf.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) > 0 {
+			r.forget(events[0].ID)
+		}
+
+		var (
+			ids        []int64
+			publishErr error
+		)
+		for _, e := range events {
+			if publishErr = r.publish(ctx, e); publishErr != nil {
+				break
+			}
+			ids = append(ids, e.ID)
+		}
+
+		if len(ids) > 0 {
+			query := "UPDATE outbox SET published_at = now() WHERE id = ANY($1)"
+			if _, err := tx.Exec(ctx, query, ids); err != nil {
+				return fmt.Errorf("mark published: %w", err)
+			}
+		}
+		published = len(ids)
+
+		// each batch removes up to as many expired events as it may publish
+		query := "DELETE FROM outbox WHERE id IN (SELECT id FROM outbox " +
+			"WHERE published_at < now() - $1::interval ORDER BY id LIMIT $2)"
+		if _, err := tx.Exec(ctx, query, r.conf.Retention, r.conf.BatchSize); err != nil {
+			return fmt.Errorf("prune published: %w", err)
+		}
+
+		// commit the published prefix even if a later event failed
+		if publishErr != nil {
+			r.logger.Error(publishErr, "relay outbox")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("relay tx: %w", err)
+	}
+
+	return published, nil
+}
+
+// publish delivers the event to the publishers that did not take it yet.
+func (r *Relay) publish(ctx context.Context, e Event) error {
+	done := r.delivered[e.ID]
+	if done == nil {
+		done = make([]bool, len(r.publishers))
+	}
+
+	for i, p := range r.publishers {
+		if done[i] {
+			continue
+		}
+		if err := p.Publish(ctx, e); err != nil {
+			r.delivered[e.ID] = done
+			return fmt.Errorf("publish event %d: %w", e.ID, err)
+		}
+		done[i] = true
+	}
+	delete(r.delivered, e.ID)
+
+	return nil
+}
+
+// forget drops the deliveries of events before the first pending one,
+// they were marked published.
+func (r *Relay) forget(first int64) {
+	for id := range r.delivered {
+		if id < first {
+			delete(r.delivered, id)
+		}
+	}
+}
+
+func pendingEvents(ctx context.Context, tx pgx.Tx, limit int) ([]Event, error) {
+	query := "SELECT id, event_type, schema_version, aggregate_id, created_at, payload " +
+		"FROM outbox WHERE published_at IS NULL " +
+		"ORDER BY id LIMIT $1"
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var res []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Version, &e.AggregateID, &e.OccurredAt, &e.Payload); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		res = append(res, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return res, nil
+}
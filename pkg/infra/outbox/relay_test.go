@@ -0,0 +1,183 @@
+// nolint:testpackage // faking the database connection
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+// fakeOutbox serves the queries of the relay from a slice of events.
+type fakeOutbox struct {
+	events    []Event
+	published map[int64]bool
+	// locked is set while another relay drains the outbox.
+	locked bool
+	// expired is set once the published events are past the retention.
+	expired bool
+}
+
+func (o *fakeOutbox) BeginFunc(_ context.Context, f func(pgx.Tx) error) error {
+	return f(&fakeOutboxTx{outbox: o})
+}
+
+type fakeOutboxTx struct {
+	pgx.Tx
+	outbox *fakeOutbox
+}
+
+func (tx *fakeOutboxTx) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return fakeLockRow{locked: !tx.outbox.locked}
+}
+
+type fakeLockRow struct {
+	locked bool
+}
+
+func (r fakeLockRow) Scan(dest ...interface{}) error {
+	*dest[0].(*bool) = r.locked
+	return nil
+}
+
+func (tx *fakeOutboxTx) Query(_ context.Context, _ string, args ...interface{}) (pgx.Rows, error) {
+	var pending []Event
+	for _, e := range tx.outbox.events {
+		if !tx.outbox.published[e.ID] && len(pending) < args[0].(int) {
+			pending = append(pending, e)
+		}
+	}
+
+	return &fakeOutboxRows{events: pending, i: -1}, nil
+}
+
+func (tx *fakeOutboxTx) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if strings.HasPrefix(sql, "DELETE") {
+		var kept []Event
+		for _, e := range tx.outbox.events {
+			if !tx.outbox.expired || !tx.outbox.published[e.ID] {
+				kept = append(kept, e)
+			}
+		}
+		tx.outbox.events = kept
+		return pgconn.CommandTag("DELETE"), nil
+	}
+
+	for _, id := range args[0].([]int64) {
+		tx.outbox.published[id] = true
+	}
+
+	return pgconn.CommandTag("UPDATE"), nil
+}
+
+type fakeOutboxRows struct {
+	pgx.Rows
+	events []Event
+	i      int
+}
+
+func (r *fakeOutboxRows) Next() bool {
+	r.i++
+	return r.i < len(r.events)
+}
+
+func (r *fakeOutboxRows) Scan(dest ...interface{}) error {
+	e := r.events[r.i]
+	*dest[0].(*int64) = e.ID
+	*dest[1].(*string) = e.Type
+	*dest[2].(*int) = e.Version
+	*dest[3].(*int) = e.AggregateID
+	*dest[4].(*time.Time) = e.OccurredAt
+	*dest[5].(*json.RawMessage) = e.Payload
+
+	return nil
+}
+
+func (r *fakeOutboxRows) Close() {}
+
+func (r *fakeOutboxRows) Err() error {
+	return nil
+}
+
+// recordingPublisher records the ids of the events it is given
+// and fails the ones listed in fail once.
+type recordingPublisher struct {
+	got  []int64
+	fail map[int64]bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e Event) error {
+	p.got = append(p.got, e.ID)
+	if p.fail[e.ID] {
+		delete(p.fail, e.ID)
+		return errors.New("unavailable")
+	}
+
+	return nil
+}
+
+func TestRelayBatch(t *testing.T) {
+	outbox := &fakeOutbox{published: map[int64]bool{}}
+	for id := int64(1); id <= 3; id++ {
+		outbox.events = append(outbox.events, Event{ID: id, Type: "company.created", Version: 1, AggregateID: int(id)})
+	}
+
+	first := &recordingPublisher{}
+	second := &recordingPublisher{fail: map[int64]bool{2: true}}
+	r := NewRelay(nil, []Publisher{first, second}, log.Logger, Config{BatchSize: 10})
+	r.conn = outbox
+
+	// nothing is published while another relay drains the outbox
+	outbox.locked = true
+	if n, err := r.RelayBatch(context.Background()); err != nil || n != 0 || len(first.got) != 0 {
+		t.Fatalf(`RelayBatch() = %d, %v, published %v, want nothing`, n, err, first.got)
+	}
+	outbox.locked = false
+
+	// publishing stops at the failed event
+	n, err := r.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf(`RelayBatch() error = %v`, err)
+	}
+	if n != 1 || !outbox.published[1] || outbox.published[2] {
+		t.Fatalf(`RelayBatch() = %d, published %v, want event 1 only`, n, outbox.published)
+	}
+
+	// the retry skips the publisher that took the event,
+	// deliveries of events before the pending ones are forgotten
+	r.delivered[0] = []bool{true, false}
+	n, err = r.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf(`RelayBatch() error = %v`, err)
+	}
+	if n != 2 || !outbox.published[2] || !outbox.published[3] {
+		t.Fatalf(`RelayBatch() = %d, published %v, want events 2 and 3`, n, outbox.published)
+	}
+
+	if got := fmt.Sprint(first.got); got != "[1 2 3]" {
+		t.Fatalf(`first publisher got %s, want every event once`, got)
+	}
+	if got := fmt.Sprint(second.got); got != "[1 2 2 3]" {
+		t.Fatalf(`second publisher got %s, want the failed event again`, got)
+	}
+	if len(r.delivered) != 0 {
+		t.Fatalf(`expected no deliveries left, got %v`, r.delivered)
+	}
+
+	// published events are removed after the retention
+	if _, err := r.RelayBatch(context.Background()); err != nil || len(outbox.events) != 3 {
+		t.Fatalf(`RelayBatch() error = %v, %d events left, want all kept`, err, len(outbox.events))
+	}
+	outbox.expired = true
+	if _, err := r.RelayBatch(context.Background()); err != nil || len(outbox.events) != 0 {
+		t.Fatalf(`RelayBatch() error = %v, %d events left, want none`, err, len(outbox.events))
+	}
+}
@@ -0,0 +1,33 @@
+// Package webhook delivers change events to webhook subscriptions.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/infra/outbox"
+)
+
+// Dispatcher is an outbox publisher scheduling event deliveries to the subscribed webhooks.
+type Dispatcher struct {
+	repo webhook.Repository
+}
+
+func NewDispatcher(repo webhook.Repository) *Dispatcher {
+	return &Dispatcher{repo: repo}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, e outbox.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	if err := d.repo.Enqueue(ctx, e.ID, e.Type, body); err != nil {
+		return fmt.Errorf("enqueue webhook jobs: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Delivery headers.
+const (
+	HeaderSignature = "Webhook-Signature"
+	HeaderEventID   = "Webhook-Event-Id"
+	HeaderEventType = "Webhook-Event-Type"
+	HeaderAttempt   = "Webhook-Attempt"
+)
+
+// Sign returns the signature header value of a delivery body sent at the time.
+//
+// The value has the form "t=<unix seconds>,v1=<hex>", where the hex part is
+// HMAC-SHA256 keyed with the secret over "<unix seconds>.<body>".
+// Receivers should recompute it and reject stale timestamps to prevent replays.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+// Worker defaults.
+const (
+	DefaultInterval     = time.Second
+	DefaultBatchSize    = 20
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultBackoffBase  = 10 * time.Second
+	DefaultBackoffMax   = time.Hour
+	DefaultDisableAfter = 20
+)
+
+// maxErrorLen limits the error recorded in the delivery log.
+const maxErrorLen = 512
+
+type Config struct {
+	// Interval between polls for due jobs.
+	Interval  time.Duration
+	BatchSize int
+	// Timeout of a single delivery request.
+	Timeout time.Duration
+	// MaxAttempts per event and webhook, the event is dropped afterwards.
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry, it doubles
+	// with every attempt up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// DisableAfter consecutive failed attempts the webhook is disabled.
+	DisableAfter int
+}
+
+func (c *Config) setDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.BackoffBase <= 0 {
+		c.BackoffBase = DefaultBackoffBase
+	}
+	if c.BackoffMax <= 0 {
+		c.BackoffMax = DefaultBackoffMax
+	}
+	if c.DisableAfter <= 0 {
+		c.DisableAfter = DefaultDisableAfter
+	}
+}
+
+// Worker sends due deliveries and schedules retries of the failed ones.
+type Worker struct {
+	repo   webhook.Repository
+	client *http.Client
+	logger log.Interface
+	conf   Config
+	now    func() time.Time
+}
+
+func NewWorker(repo webhook.Repository, logger log.Interface, conf Config) *Worker {
+	conf.setDefaults()
+
+	return &Worker{
+		repo:   repo,
+		client: &http.Client{Timeout: conf.Timeout},
+		logger: logger,
+		conf:   conf,
+		now:    time.Now,
+	}
+}
+
+// Run sends deliveries until the context is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := w.DeliverBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				w.logger.Error(err, "deliver webhooks")
+			}
+			if err != nil || n < w.conf.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverBatch sends a single batch of due jobs and returns the number of jobs claimed.
+func (w *Worker) DeliverBatch(ctx context.Context) (int, error) {
+	// lease the jobs long enough to get through the whole batch
+	lease := w.conf.Timeout*time.Duration(w.conf.BatchSize) + time.Minute
+
+	jobs, err := w.repo.Claim(ctx, w.conf.BatchSize, lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim jobs: %w", err)
+	}
+
+	for _, job := range jobs {
+		d := w.deliver(ctx, job)
+
+		if d.Succeeded() {
+			err = w.repo.Complete(ctx, job, d)
+		} else {
+			var retryAt *time.Time
+			if job.Attempt < w.conf.MaxAttempts {
+				at := w.now().Add(w.backoff(job.Attempt))
+				retryAt = &at
+			}
+			err = w.repo.Fail(ctx, job, d, retryAt, w.conf.DisableAfter)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("record delivery: %w", err)
+		}
+	}
+
+	return len(jobs), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, job webhook.Job) webhook.Delivery {
+	d := webhook.Delivery{
+		WebhookID: job.Webhook.ID,
+		EventID:   job.EventID,
+		EventType: job.EventType,
+		Attempt:   job.Attempt,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Webhook.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		d.Error = truncate(err.Error())
+		return d
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(job.EventID, 10))
+	req.Header.Set(HeaderEventType, job.EventType)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(job.Attempt))
+	req.Header.Set(HeaderSignature, Sign(job.Webhook.Secret, w.now(), job.Payload))
+
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	d.Duration = time.Since(start)
+	if err != nil {
+		d.Error = truncate(err.Error())
+		return d
+	}
+	defer resp.Body.Close()
+
+	// drain a bit of the body to let the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	d.StatusCode = resp.StatusCode
+	if !d.Succeeded() {
+		d.Error = "unexpected status " + strconv.Itoa(resp.StatusCode)
+	}
+
+	return d
+}
+
+// backoff returns the delay after the failed attempt, exponential
+// with a random jitter of up to a half of it.
+func (w *Worker) backoff(attempt int) time.Duration {
+	d := w.conf.BackoffBase
+	for i := 1; i < attempt && d < w.conf.BackoffMax; i++ {
+		d *= 2
+	}
+	if d > w.conf.BackoffMax {
+		d = w.conf.BackoffMax
+	}
+
+	half := d / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1)) // nolint:gosec // jitter needs no crypto
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLen {
+		return s[:maxErrorLen]
+	}
+
+	return s
+}
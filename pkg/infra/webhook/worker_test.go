@@ -0,0 +1,127 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/domain/webhook"
+	mocks "github.com/nyzhehorodov/apicompanies/pkg/domain/webhook/mocks"
+	iwebhook "github.com/nyzhehorodov/apicompanies/pkg/infra/webhook"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/log"
+)
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	expected := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := iwebhook.Sign("secret", ts, body); got != expected {
+		t.Fatalf(`expected %q, got %q`, expected, got)
+	}
+}
+
+func TestWorkerDeliverBatch(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.HasPrefix(r.Header.Get(iwebhook.HeaderSignature), "t=") || string(body) != `{"id":1}` {
+			t.Errorf(`unexpected delivery %q signed %q`, body, r.Header.Get(iwebhook.HeaderSignature))
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		status  int
+		attempt int
+		expect  func(repo *mocks.MockRepository)
+	}{
+		{
+			name:    "success",
+			status:  http.StatusNoContent,
+			attempt: 1,
+			expect: func(repo *mocks.MockRepository) {
+				repo.EXPECT().Complete(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ webhook.Job, d webhook.Delivery) error {
+						if d.StatusCode != http.StatusNoContent || d.Attempt != 1 {
+							t.Fatalf(`unexpected delivery %+v`, d)
+						}
+						return nil
+					})
+			},
+		},
+		{
+			name:    "retry",
+			status:  http.StatusBadGateway,
+			attempt: 2,
+			expect: func(repo *mocks.MockRepository) {
+				repo.EXPECT().Fail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), 5).
+					DoAndReturn(func(_ context.Context, _ webhook.Job, d webhook.Delivery, at *time.Time, _ int) error {
+						// second attempt waits 2s with up to a half of jitter
+						if at == nil || time.Until(*at) < 900*time.Millisecond || time.Until(*at) > 2*time.Second {
+							t.Fatalf(`unexpected retry at %v`, at)
+						}
+						if d.Error == "" {
+							t.Fatalf(`expected delivery error`)
+						}
+						return nil
+					})
+			},
+		},
+		{
+			name:    "give up",
+			status:  http.StatusInternalServerError,
+			attempt: 3,
+			expect: func(repo *mocks.MockRepository) {
+				repo.EXPECT().Fail(gomock.Any(), gomock.Any(), gomock.Any(), nil, 5).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+
+			repo := mocks.NewMockRepository(gomock.NewController(t))
+			job := webhook.Job{
+				ID:        1,
+				Webhook:   webhook.Webhook{ID: 1, URL: srv.URL, Secret: "secret"},
+				EventID:   1,
+				EventType: "company.created",
+				Payload:   []byte(`{"id":1}`),
+				Attempt:   tt.attempt,
+			}
+			repo.EXPECT().Claim(gomock.Any(), 10, gomock.Any()).Return([]webhook.Job{job}, nil)
+			tt.expect(repo)
+
+			worker := iwebhook.NewWorker(repo, log.Logger, iwebhook.Config{
+				BatchSize:    10,
+				MaxAttempts:  3,
+				BackoffBase:  time.Second,
+				DisableAfter: 5,
+			})
+
+			n, err := worker.DeliverBatch(context.Background())
+			if err != nil {
+				t.Fatalf(`unexpected error %v`, err)
+			}
+			if n != 1 {
+				t.Fatalf(`expected 1 job, got %d`, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// APIKeyHeader is the header carrying an API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator checks an API key and returns the principal
+// it belongs to along with the granted scopes.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (principal string, scopes []string, err error)
+}
+
+// ScopeFunc returns the scope required to serve the request,
+// an empty scope means any authenticated key is accepted.
+type ScopeFunc func(r *http.Request) string
+
+// APIKeyAuth returns a middleware that authenticates the X-API-Key header
+// and checks that the key grants the scope required by the route.
+// Requests without the header are rejected unless passThrough is set,
+// which lets another authentication middleware, e.g. BearerAuth, handle them.
+func APIKeyAuth(auth APIKeyAuthenticator, scope ScopeFunc, passThrough bool) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				if passThrough {
+					next(w, r)
+					return
+				}
+				writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "authentication required")
+				return
+			}
+
+			principal, scopes, err := auth.AuthenticateAPIKey(r.Context(), key)
+			if err != nil {
+				writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "invalid api key")
+				return
+			}
+
+			if required := scope(r); required != "" && !hasScope(scopes, required) {
+				writeProblem(w, r, http.StatusForbidden, problemForbidden, "api key lacks scope "+required)
+				return
+			}
+
+			next(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		}
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of parent context with the authenticated principal.
+func WithPrincipal(parent context.Context, principal string) context.Context {
+	return context.WithValue(parent, principalKey{}, principal)
+}
+
+// Principal returns the principal authenticated by BearerAuth or APIKeyAuth
+// or an empty string for anonymous requests.
+func Principal(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/jwt"
+)
+
+type claimsKey struct{}
+
+// BearerAuth returns a middleware that requires a valid JWT
+// in the "Authorization: Bearer" header. The verified claims
+// are put into the request context, see Claims, and the token subject
+// becomes the request principal. Requests already authenticated
+// by an earlier middleware, e.g. APIKeyAuth, are passed through.
+// Use PathPrefix to attach it to a subset of routes.
+func BearerAuth(verifier *jwt.Verifier) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if Principal(r.Context()) != "" {
+				next(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "authentication required")
+				return
+			}
+
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "invalid token")
+				return
+			}
+
+			ctx := WithClaims(r.Context(), claims)
+			next(w, r.WithContext(WithPrincipal(ctx, claims.Subject)))
+		}
+	}
+}
+
+// TokenScope returns a middleware that requires the token verified
+// by BearerAuth to grant the scope required by the route, see Claims.Scopes.
+// Requests authenticated otherwise, e.g. by APIKeyAuth which checks
+// key scopes itself, are passed through. Mount it after BearerAuth.
+func TokenScope(scope ScopeFunc) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims := Claims(r.Context())
+			if claims == nil {
+				next(w, r)
+				return
+			}
+
+			if required := scope(r); required != "" && !hasScope(claims.Scopes(), required) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+required+`"`)
+				writeProblem(w, r, http.StatusForbidden, problemForbidden, "token lacks scope "+required)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+
+	const prefix = "bearer "
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(h[len(prefix):]), true
+}
+
+// WithClaims returns a copy of parent context with the token claims.
+func WithClaims(parent context.Context, claims *jwt.Claims) context.Context {
+	return context.WithValue(parent, claimsKey{}, claims)
+}
+
+// Claims returns the claims stored by BearerAuth or nil.
+func Claims(ctx context.Context) *jwt.Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*jwt.Claims)
+	return claims
+}
@@ -0,0 +1,176 @@
+package httpserver_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/jwt"
+)
+
+var testSecret = []byte("secret")
+
+func signHS256(t *testing.T, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": jwt.HS256, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf(`marshal header: %v`, err)
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf(`marshal claims: %v`, err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	mac := hmac.New(sha256.New, testSecret)
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestBearerAuth(t *testing.T) {
+	keys := &jwt.KeySet{}
+	keys.AddHMAC("", testSecret)
+	verifier := jwt.NewVerifier(keys, jwt.Config{})
+
+	handler := httpserver.BearerAuth(verifier)(func(w http.ResponseWriter, r *http.Request) {
+		if claims := httpserver.Claims(r.Context()); claims == nil || claims.Subject != "alice" {
+			t.Fatalf(`expected the claims of alice, got %+v`, claims)
+		}
+		_, _ = w.Write([]byte(httpserver.Principal(r.Context())))
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{
+			name: "missing token",
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:          "other scheme",
+			authorization: "Basic YWxpY2U6c2VjcmV0",
+			want:          http.StatusUnauthorized,
+		},
+		{
+			name:          "bad token",
+			authorization: "Bearer abc",
+			want:          http.StatusUnauthorized,
+		},
+		{
+			name:          "expired token",
+			authorization: "Bearer " + signHS256(t, "", map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()}),
+			want:          http.StatusUnauthorized,
+		},
+		{
+			name:          "valid token",
+			authorization: "Bearer " + signHS256(t, "", map[string]interface{}{"sub": "alice"}),
+			want:          http.StatusOK,
+		},
+		{
+			name:          "token with a kid",
+			authorization: "bearer " + signHS256(t, "k1", map[string]interface{}{"sub": "alice"}),
+			want:          http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/companies", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf(`expected status %d, got %d: %s`, tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf(`expected a WWW-Authenticate challenge`)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Fatalf(`expected a problem response, got %q`, rec.Header().Get("Content-Type"))
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != "alice" {
+				t.Fatalf(`expected principal %q, got %q`, "alice", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTokenScope(t *testing.T) {
+	keys := &jwt.KeySet{}
+	keys.AddHMAC("", testSecret)
+	verifier := jwt.NewVerifier(keys, jwt.Config{})
+
+	scope := func(*http.Request) string { return "apikeys:admin" }
+	handler := httpserver.BearerAuth(verifier)(httpserver.TokenScope(scope)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   int
+	}{
+		{
+			name:   "no scope",
+			claims: map[string]interface{}{"sub": "alice"},
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "other scope",
+			claims: map[string]interface{}{"sub": "alice", "scope": "companies:read companies:write"},
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "scope claim",
+			claims: map[string]interface{}{"sub": "alice", "scope": "companies:read apikeys:admin"},
+			want:   http.StatusNoContent,
+		},
+		{
+			name:   "scp claim",
+			claims: map[string]interface{}{"sub": "alice", "scp": []string{"apikeys:admin"}},
+			want:   http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/apikeys", nil)
+			req.Header.Set("Authorization", "Bearer "+signHS256(t, "", tt.claims))
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf(`expected status %d, got %d: %s`, tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want == http.StatusForbidden && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Fatalf(`expected a problem response, got %q`, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	// principals authenticated otherwise are checked by their own middleware
+	pass := httpserver.TokenScope(scope)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/v1/apikeys", nil)
+	rec := httptest.NewRecorder()
+	pass(rec, req.WithContext(httpserver.WithPrincipal(req.Context(), "key-owner")))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf(`expected status %d without claims, got %d`, http.StatusNoContent, rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// CountryResolver resolves a client IP address to an ISO 3166-1 alpha-2 country code.
+type CountryResolver interface {
+	Country(ctx context.Context, ip string) (string, error)
+}
+
+// CountryRestriction returns a middleware allowing mutating requests
+// (POST, PUT, PATCH and DELETE) only from the listed countries.
+// Other methods pass through. Requests from other countries get 403,
+// requests whose country cannot be resolved get 503. Requests from
+// non-routable addresses, e.g. private ones, have no country and get 403
+// without asking the resolver.
+// If trustForwarded is set the client IP is taken from X-Forwarded-For.
+func CountryRestriction(resolver CountryResolver, allowed []string, trustForwarded bool) func(http.HandlerFunc) http.HandlerFunc {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, c := range allowed {
+		allowedSet[strings.ToUpper(c)] = struct{}{}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !isMutating(r.Method) {
+				next(w, r)
+				return
+			}
+
+			ip := net.ParseIP(ClientIP(r, trustForwarded))
+			if ip == nil || !isRoutable(ip) {
+				writeProblem(w, r, http.StatusForbidden, problemCountryNotAllowed,
+					"the country of the client address cannot be determined")
+				return
+			}
+
+			country, err := resolver.Country(r.Context(), ip.String())
+			if err != nil {
+				writeProblem(w, r, http.StatusServiceUnavailable, problemUnavailable, "cannot resolve client country")
+				return
+			}
+
+			if _, ok := allowedSet[strings.ToUpper(country)]; !ok {
+				writeProblem(w, r, http.StatusForbidden, problemCountryNotAllowed,
+					"requests from your country are not allowed")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// isRoutable reports whether the address is a public one, which can be geolocated.
+func isRoutable(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// ClientIP returns the IP address of the client.
+// If trustForwarded is set the first address of X-Forwarded-For is preferred,
+// it must be set only behind a proxy that overwrites the header.
+func ClientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,126 @@
+package httpserver_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+// countries resolves addresses from a map and records the lookups.
+type countries struct {
+	byIP    map[string]string
+	lookups []string
+}
+
+func (c *countries) Country(_ context.Context, ip string) (string, error) {
+	c.lookups = append(c.lookups, ip)
+
+	country, ok := c.byIP[ip]
+	if !ok {
+		return "", errors.New("lookup failed")
+	}
+
+	return country, nil
+}
+
+func TestCountryRestriction(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		remoteAddr     string
+		forwarded      string
+		trustForwarded bool
+		want           int
+		wantLookup     string
+	}{
+		{
+			name:       "reads pass",
+			method:     http.MethodGet,
+			remoteAddr: "203.0.113.9:1234",
+			want:       http.StatusNoContent,
+		},
+		{
+			name:       "allowed",
+			method:     http.MethodPost,
+			remoteAddr: "203.0.113.7:1234",
+			want:       http.StatusNoContent,
+			wantLookup: "203.0.113.7",
+		},
+		{
+			name:       "blocked",
+			method:     http.MethodDelete,
+			remoteAddr: "203.0.113.8:1234",
+			want:       http.StatusForbidden,
+			wantLookup: "203.0.113.8",
+		},
+		{
+			name:           "trusted forwarded header",
+			method:         http.MethodPost,
+			remoteAddr:     "10.0.0.2:1234",
+			forwarded:      "203.0.113.7, 10.0.0.1",
+			trustForwarded: true,
+			want:           http.StatusNoContent,
+			wantLookup:     "203.0.113.7",
+		},
+		{
+			name:       "untrusted forwarded header",
+			method:     http.MethodPost,
+			remoteAddr: "203.0.113.8:1234",
+			forwarded:  "203.0.113.7",
+			want:       http.StatusForbidden,
+			wantLookup: "203.0.113.8",
+		},
+		{
+			name:       "resolver error",
+			method:     http.MethodPut,
+			remoteAddr: "203.0.113.9:1234",
+			want:       http.StatusServiceUnavailable,
+			wantLookup: "203.0.113.9",
+		},
+		{
+			name:       "private address",
+			method:     http.MethodPost,
+			remoteAddr: "192.168.1.10:1234",
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "loopback address",
+			method:     http.MethodPatch,
+			remoteAddr: "[::1]:1234",
+			want:       http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &countries{byIP: map[string]string{"203.0.113.7": "cy", "203.0.113.8": "RU"}}
+			handler := httpserver.CountryRestriction(resolver, []string{"CY", "GB"}, tt.trustForwarded)(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNoContent)
+				})
+
+			req := httptest.NewRequest(tt.method, "/v1/companies", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf(`expected status %d, got %d: %s`, tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want != http.StatusNoContent && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Fatalf(`expected a problem response, got %q`, rec.Header().Get("Content-Type"))
+			}
+			if got := strings.Join(resolver.lookups, ","); got != tt.wantLookup {
+				t.Fatalf(`expected lookups %q, got %q`, tt.wantLookup, got)
+			}
+		})
+	}
+}
@@ -16,6 +16,14 @@ import (
 type Server struct {
 	router     *httprouter.Router
 	middleware []middleware
+	// verbs holds handlers of custom method routes by the method and the route without the verb.
+	verbs map[string]map[string]http.HandlerFunc
+	// static holds handlers of routes without parameters by the method
+	// and the path. They are matched before routing, so that they may
+	// share a segment with a parameter, e.g. "/v1/companies/search"
+	// and "/v1/companies/:id", and may end with a custom verb,
+	// e.g. "/v1/companies:batch", which the router takes for a parameter.
+	static map[string]http.HandlerFunc
 
 	httpserver *http.Server
 
@@ -30,7 +38,10 @@ func New() *Server {
 
 	srv := &Server{
 		router: router,
+		verbs:  map[string]map[string]http.HandlerFunc{},
 		done:   make(chan struct{}),
+
+		static: map[string]http.HandlerFunc{},
 	}
 
 	srv.SetNotFoundHandler(notImplementedHandler)
@@ -95,6 +106,16 @@ func (srv *Server) AddMiddleware(handler func(http.HandlerFunc) http.HandlerFunc
 	srv.middleware = append(srv.middleware, m)
 }
 
+// ServeHTTP makes the server implement the http.Handler interface.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h, ok := srv.static[r.Method+" "+r.URL.Path]; ok {
+		h(w, r)
+		return
+	}
+
+	srv.router.ServeHTTP(w, r)
+}
+
 // Listen starts serving at specified address and port.
 // Always returns not nil error.
 func (srv *Server) Listen(addr string) error {
@@ -108,7 +129,7 @@ func (srv *Server) ListenTLS(addr, certFile, keyFile string) error {
 }
 
 func (srv *Server) listen(addr string, listener func(server *http.Server) error) error {
-	server := &http.Server{Addr: addr, Handler: srv.router}
+	server := &http.Server{Addr: addr, Handler: srv}
 	srv.httpserver = server
 	return listener(server)
 }
@@ -123,7 +144,14 @@ func (srv *Server) HandlePUT(path string, handler http.HandlerFunc) {
 	srv.handleFunc(path, handler, http.MethodPut)
 }
 
+// HandlePATCH adds a new PATCH handler to Server.
+func (srv *Server) HandlePATCH(path string, handler http.HandlerFunc) {
+	srv.handleFunc(path, handler, http.MethodPatch)
+}
+
 // HandlePOST adds a new POST handler to Server.
+// The path may end with a custom verb, e.g. "/v1/companies:batch"
+// or "/v1/companies/:id:restore".
 func (srv *Server) HandlePOST(path string, handler http.HandlerFunc) {
 	srv.handleFunc(path, handler, http.MethodPost)
 }
@@ -152,17 +180,97 @@ func (srv *Server) handleFunc(path string, handler http.HandlerFunc, method stri
 	// apply middleware
 	for i := len(srv.middleware) - 1; i >= 0; i-- {
 		m := srv.middleware[i]
-		if m.opts.prefix != "" && !strings.HasPrefix(path, m.opts.prefix) {
+		if !m.opts.applies(path) {
 			continue
 		}
 		handler = m.f(handler)
 	}
 
+	if base, param, verb, ok := splitVerb(path); ok {
+		srv.handleVerb(method, base, param, verb, handler)
+		return
+	}
+	if !hasParams(path) {
+		srv.static[method+" "+path] = handler
+		return
+	}
+
 	h := paramsMiddleware(handler)
 
 	srv.router.Handle(method, path, h)
 }
 
+// hasParams reports whether the path has a parameter segment,
+// a ':' within a segment starts a custom verb instead.
+func hasParams(path string) bool {
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitVerb splits a custom method route, whose last segment is a parameter
+// followed by a verb, e.g. "/v1/companies/:id:restore" into
+// "/v1/companies/:id", "id" and "restore".
+func splitVerb(path string) (base, param, verb string, ok bool) {
+	i := strings.LastIndex(path, "/")
+	seg := path[i+1:]
+	if !strings.HasPrefix(seg, ":") {
+		return "", "", "", false
+	}
+
+	j := strings.Index(seg[1:], ":")
+	if j < 0 {
+		return "", "", "", false
+	}
+	j++
+
+	return path[:i+1] + seg[:j], seg[1:j], seg[j+1:], true
+}
+
+// handleVerb registers a custom method route. Routes with different verbs
+// share a single router entry dispatching on the suffix of the parameter,
+// so the same method cannot be registered for the route without a verb.
+func (srv *Server) handleVerb(method, base, param, verb string, handler http.HandlerFunc) {
+	key := method + " " + base
+
+	verbs, ok := srv.verbs[key]
+	if !ok {
+		verbs = map[string]http.HandlerFunc{}
+		srv.verbs[key] = verbs
+
+		srv.router.Handle(method, base, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			value := ps.ByName(param)
+
+			i := strings.LastIndex(value, ":")
+			if i < 0 {
+				srv.router.NotFound.ServeHTTP(w, r)
+				return
+			}
+			h, ok := verbs[value[i+1:]]
+			if !ok {
+				srv.router.NotFound.ServeHTTP(w, r)
+				return
+			}
+
+			params := make(httprouter.Params, 0, len(ps))
+			for _, p := range ps {
+				if p.Key == param {
+					p.Value = value[:i]
+				}
+				params = append(params, p)
+			}
+
+			paramsMiddleware(h)(w, r, params)
+		})
+	}
+
+	verbs[verb] = handler
+}
+
 func paramsMiddleware(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request, httprouter.Params) {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		ctx := r.Context()
@@ -0,0 +1,194 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header carrying the client chosen idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from the store.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLen limits the length of an idempotency key.
+const maxIdempotencyKeyLen = 255
+
+const (
+	// idempotencyPollDelay is the first delay between the attempts
+	// to claim a key in use, doubled up to maxIdempotencyPollDelay.
+	idempotencyPollDelay    = 50 * time.Millisecond
+	maxIdempotencyPollDelay = time.Second
+)
+
+// IdempotentResponse is a recorded response along with the fingerprint
+// of the request it was produced for.
+type IdempotentResponse struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// ErrIdempotencyKeyInUse is returned by IdempotencyStore.Lock for a key
+// claimed by a request still being served.
+var ErrIdempotencyKeyInUse = errors.New("idempotency key is in use")
+
+// IdempotencyStore keeps the responses of requests by their idempotency keys.
+type IdempotencyStore interface {
+	// Lock claims the key of the principal until fn returns. A key claimed
+	// by a concurrent request is reported with ErrIdempotencyKeyInUse
+	// without calling fn. fn gets the stored response, nil if the key is new
+	// or has expired, and returns the response to store, nil to release the key.
+	// Stores should not hold resources needed by fn, e.g. database connections,
+	// while it runs.
+	Lock(ctx context.Context, principal, key string, fn func(stored *IdempotentResponse) (*IdempotentResponse, error)) error
+}
+
+// Idempotency returns a middleware that makes POST requests carrying
+// the Idempotency-Key header safe to retry. The response to the first
+// request with a key is recorded and replayed for later requests with
+// the same key and body, reusing the key with a different request gets 422.
+// A request arriving while another one with the key is served waits up to
+// lockWait for it to finish and replays its response, or gets 409.
+// Keys are scoped by the principal, so the middleware must come after
+// the authentication ones. Server errors are not recorded, their requests
+// can be retried with the same key. Request bodies up to maxBodySize bytes
+// are accepted, larger ones get 413.
+func Idempotency(store IdempotencyStore, maxBodySize int64, lockWait time.Duration) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				writeProblem(w, r, http.StatusBadRequest, problemBadRequest, "idempotency key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, problemPayloadTooLarge,
+					"request body is too large for an idempotent request")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(r, body)
+
+			var (
+				resp     *IdempotentResponse
+				replayed bool
+			)
+			// a response that could not be stored is sent anyway,
+			// a retry with the key runs the request again
+			serve := func(stored *IdempotentResponse) (*IdempotentResponse, error) {
+				if stored != nil {
+					resp, replayed = stored, true
+					return nil, nil
+				}
+
+				rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+				next(rec, r)
+
+				resp = &IdempotentResponse{Fingerprint: fingerprint, Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+				if resp.Status >= http.StatusInternalServerError {
+					return nil, nil
+				}
+
+				return resp, nil
+			}
+			err = lockIdempotencyKey(r.Context(), store, Principal(r.Context()), key, lockWait, serve)
+			if errors.Is(err, ErrIdempotencyKeyInUse) {
+				writeProblem(w, r, http.StatusConflict, problemIdempotencyKeyInUse,
+					"a request with the idempotency key is in progress, retry later")
+				return
+			}
+			if resp == nil {
+				writeProblem(w, r, http.StatusServiceUnavailable, problemUnavailable, "idempotency store is unavailable")
+				return
+			}
+			if replayed && resp.Fingerprint != fingerprint {
+				writeProblem(w, r, http.StatusUnprocessableEntity, problemIdempotencyKeyReuse,
+					"idempotency key was used for a different request")
+				return
+			}
+
+			for k, v := range resp.Header {
+				w.Header()[k] = v
+			}
+			if replayed {
+				w.Header().Set(IdempotentReplayedHeader, "true")
+			}
+			w.WriteHeader(resp.Status)
+			_, _ = w.Write(resp.Body)
+		}
+	}
+}
+
+// lockIdempotencyKey calls store.Lock until the key is no longer
+// in use by a concurrent request, for up to wait.
+func lockIdempotencyKey(
+	ctx context.Context, store IdempotencyStore, principal, key string, wait time.Duration,
+	fn func(stored *IdempotentResponse) (*IdempotentResponse, error),
+) error {
+	deadline := time.Now().Add(wait)
+	delay := idempotencyPollDelay
+	for {
+		err := store.Lock(ctx, principal, key, fn)
+		if !errors.Is(err, ErrIdempotencyKeyInUse) || time.Now().Add(delay).After(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxIdempotencyPollDelay {
+			delay = maxIdempotencyPollDelay
+		}
+	}
+}
+
+// requestFingerprint identifies the request by the method, the URL and the body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	_, _ = h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder buffers the response of the handler.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = status
+	rec.wroteHeader = true
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(p)
+}
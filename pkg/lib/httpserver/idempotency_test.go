@@ -0,0 +1,129 @@
+package httpserver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+// memStore claims keys in a map.
+type memStore struct {
+	mu      sync.Mutex
+	claimed map[string]bool
+	resps   map[string]*httpserver.IdempotentResponse
+}
+
+func newMemStore() *memStore {
+	return &memStore{claimed: map[string]bool{}, resps: map[string]*httpserver.IdempotentResponse{}}
+}
+
+func (s *memStore) Lock(_ context.Context, principal, key string, fn func(*httpserver.IdempotentResponse) (*httpserver.IdempotentResponse, error)) error {
+	k := principal + "/" + key
+
+	s.mu.Lock()
+	if s.claimed[k] {
+		s.mu.Unlock()
+		return httpserver.ErrIdempotencyKeyInUse
+	}
+	s.claimed[k] = true
+	stored := s.resps[k]
+	s.mu.Unlock()
+
+	resp, err := fn(stored)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claimed, k)
+	if err != nil || resp == nil {
+		return err
+	}
+	s.resps[k] = resp
+
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	next := func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Query().Get("wait") != "" {
+			close(entered)
+			<-release
+		}
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/v1/companies/7")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(strings.Repeat("x", int(n))))
+	}
+	store := newMemStore()
+	handler := httpserver.Idempotency(store, 1024, time.Minute)(next)
+	impatient := httpserver.Idempotency(store, 1024, 0)(next)
+
+	serve := func(h http.HandlerFunc, target, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(httpserver.IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	post := func(target, key, body string) *httptest.ResponseRecorder {
+		return serve(handler, target, key, body)
+	}
+
+	// a request arriving while another one with the key is served
+	// waits for it, or is rejected once it stops waiting
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- post("/v1/companies?wait=1", "k1", `{"name":"Acme"}`) }()
+	<-entered
+	rec := serve(impatient, "/v1/companies?wait=1", "k1", `{"name":"Acme"}`)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf(`expected a %d problem for a key in use, got %d %q`, http.StatusConflict, rec.Code, rec.Body.String())
+	}
+	second := make(chan *httptest.ResponseRecorder)
+	go func() { second <- post("/v1/companies?wait=1", "k1", `{"name":"Acme"}`) }()
+	close(release)
+	if rec := <-first; rec.Code != http.StatusCreated || rec.Header().Get(httpserver.IdempotentReplayedHeader) != "" {
+		t.Fatalf(`unexpected first response %d %v`, rec.Code, rec.Header())
+	}
+	if rec := <-second; rec.Code != http.StatusCreated || rec.Body.String() != "x" ||
+		rec.Header().Get(httpserver.IdempotentReplayedHeader) != "true" {
+		t.Fatalf(`expected the waiting request to replay the response, got %d %q`, rec.Code, rec.Body.String())
+	}
+
+	// later requests with the key replay the response
+	for i := 0; i < 2; i++ {
+		rec := post("/v1/companies?wait=1", "k1", `{"name":"Acme"}`)
+		if rec.Code != http.StatusCreated || rec.Body.String() != "x" || rec.Header().Get("Location") != "/v1/companies/7" ||
+			rec.Header().Get(httpserver.IdempotentReplayedHeader) != "true" {
+			t.Fatalf(`unexpected replayed response %d %q %v`, rec.Code, rec.Body.String(), rec.Header())
+		}
+	}
+	if calls != 1 {
+		t.Fatalf(`expected a single handler call, got %d`, calls)
+	}
+
+	if rec := post("/v1/companies?wait=1", "k1", `{"name":"Other"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf(`expected status %d for a reused key, got %d`, http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	// server errors are not recorded
+	post("/v1/companies?fail=1", "k2", "")
+	if rec := post("/v1/companies?fail=1", "k2", ""); rec.Code != http.StatusInternalServerError || calls != 3 {
+		t.Fatalf(`expected a retried server error, got %d after %d calls`, rec.Code, calls)
+	}
+
+	if rec := post("/v1/companies", "k3", strings.Repeat("x", 2048)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf(`expected status %d, got %d`, http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
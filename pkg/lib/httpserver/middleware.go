@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"net/http"
+	"strings"
 )
 
 type middleware struct {
@@ -11,6 +12,7 @@ type middleware struct {
 
 type middlewareOptions struct {
 	prefix string
+	except []string
 }
 
 // MiddlewareOptions is a function on the options for a middleware.
@@ -23,3 +25,25 @@ func PathPrefix(pref string) MiddlewareOption {
 		o.prefix = pref
 	}
 }
+
+// ExceptPath is an Option to exclude the route registered
+// with the path from the routes matching the prefix
+func ExceptPath(path string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.except = append(o.except, path)
+	}
+}
+
+// applies reports whether the middleware wraps the route registered with the path.
+func (o middlewareOptions) applies(path string) bool {
+	if o.prefix != "" && !strings.HasPrefix(path, o.prefix) {
+		return false
+	}
+	for _, except := range o.except {
+		if path == except {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+func TestMiddlewareOptions(t *testing.T) {
+	srv := httpserver.New()
+	srv.AddMiddleware(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Wrapped", "true")
+			next(w, r)
+		}
+	}, httpserver.PathPrefix("/v1/companies"), httpserver.ExceptPath("/v1/companies:import"))
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	srv.HandlePOST("/v1/status", ok)
+	srv.HandlePOST("/v1/companies", ok)
+	srv.HandlePOST("/v1/companies:import", ok)
+	srv.HandlePOST("/v1/companies/:id:restore", ok)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/v1/status"},
+		{path: "/v1/companies", want: true},
+		{path: "/v1/companies:import"},
+		{path: "/v1/companies/3:restore", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if got := rec.Header().Get("X-Wrapped") != ""; got != tt.want {
+				t.Fatalf(`expected wrapped %v, got %v`, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem codes of the responses written by the middlewares.
+const (
+	problemBadRequest          = "bad_request"
+	problemUnauthorized        = "unauthorized"
+	problemForbidden           = "forbidden"
+	problemCountryNotAllowed   = "country_not_allowed"
+	problemPayloadTooLarge     = "payload_too_large"
+	problemIdempotencyKeyInUse = "idempotency_key_in_use"
+	problemIdempotencyKeyReuse = "idempotency_key_reused"
+	problemUnavailable         = "unavailable"
+)
+
+// problem is an RFC 7807 problem details object
+// in the format of the responses of the API.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// writeProblem renders a problem response with the application/problem+json content type.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: RequestID(r.Context()),
+	})
+}
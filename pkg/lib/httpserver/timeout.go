@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RouteTimeout overrides the request timeout of the routes
+// starting with the prefix, a zero Timeout disables it.
+type RouteTimeout struct {
+	PathPrefix string
+	Timeout    time.Duration
+}
+
+// Timeout returns a middleware that cancels the request context after
+// the timeout, stopping the database work of the request. The longest
+// route prefix matching the request path overrides the default timeout.
+// Handlers are expected to report the context error themselves.
+func Timeout(timeout time.Duration, routes []RouteTimeout) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			d := timeout
+			matched := -1
+			for _, rt := range routes {
+				if len(rt.PathPrefix) > matched && strings.HasPrefix(r.URL.Path, rt.PathPrefix) {
+					d, matched = rt.Timeout, len(rt.PathPrefix)
+				}
+			}
+
+			if d <= 0 {
+				next(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
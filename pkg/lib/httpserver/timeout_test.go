@@ -0,0 +1,48 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nyzhehorodov/apicompanies/pkg/lib/httpserver"
+)
+
+func TestTimeout(t *testing.T) {
+	mw := httpserver.Timeout(time.Second, []httpserver.RouteTimeout{
+		{PathPrefix: "/v1/companies", Timeout: time.Minute},
+		{PathPrefix: "/v1/companies:export"},
+	})
+
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{path: "/v1/status", want: time.Second},
+		{path: "/v1/companies/3", want: time.Minute},
+		{path: "/v1/companies:export"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var (
+				deadline time.Time
+				ok       bool
+			)
+			mw(func(_ http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if tt.want == 0 {
+				if ok {
+					t.Fatalf(`expected no deadline, got %v`, deadline)
+				}
+				return
+			}
+			if left := time.Until(deadline); !ok || left > tt.want || left < tt.want-time.Second/2 {
+				t.Fatalf(`expected a deadline in %v, got %v`, tt.want, left)
+			}
+		})
+	}
+}
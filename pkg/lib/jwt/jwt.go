@@ -0,0 +1,257 @@
+// Package jwt verifies JSON Web Tokens signed with HS256, RS256 or ES256.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Package errors.
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrUnknownKey       = errors.New("no key to verify the token")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("token is expired")
+	ErrNotYetValid      = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("invalid issuer")
+	ErrInvalidAudience  = errors.New("invalid audience")
+)
+
+// Claims holds the verified token claims.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	// Raw holds all claims of the token payload.
+	Raw map[string]interface{}
+}
+
+// Scopes returns the scopes granted by the space-delimited "scope" claim
+// or, when absent, by the "scp" claim holding a string or an array of strings.
+func (c *Claims) Scopes() []string {
+	if s, ok := c.Raw["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+
+	switch scp := c.Raw["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, v := range scp {
+			if s, ok := v.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	}
+
+	return nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type payload struct {
+	Sub string   `json:"sub"`
+	Iss string   `json:"iss"`
+	Aud audience `json:"aud"`
+	Exp *float64 `json:"exp"`
+	Nbf *float64 `json:"nbf"`
+	Iat *float64 `json:"iat"`
+}
+
+// audience is a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+
+	return nil
+}
+
+type Config struct {
+	// Issuer is required to match the iss claim when set.
+	Issuer string
+	// Audience is required to be listed in the aud claim when set.
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// Verifier checks token signatures and registered claims.
+type Verifier struct {
+	keys *KeySet
+	conf Config
+	now  func() time.Time
+}
+
+func NewVerifier(keys *KeySet, conf Config) *Verifier {
+	return &Verifier{keys: keys, conf: conf, now: time.Now}
+}
+
+// Verify parses the compact serialized token and returns its claims
+// if the signature and the exp, nbf, iss and aud claims are valid.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, ErrMalformed
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if err := v.keys.verify(h.Alg, h.Kid, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var p payload
+	if err := decodeSegment(parts[1], &p); err != nil {
+		return nil, ErrMalformed
+	}
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrMalformed
+	}
+
+	claims := &Claims{
+		Subject:   p.Sub,
+		Issuer:    p.Iss,
+		Audience:  p.Aud,
+		ExpiresAt: numericDate(p.Exp),
+		NotBefore: numericDate(p.Nbf),
+		IssuedAt:  numericDate(p.Iat),
+		Raw:       raw,
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) validate(c *Claims) error {
+	now := v.now()
+
+	if !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt.Add(v.conf.Leeway)) {
+		return ErrExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.conf.Leeway).Before(c.NotBefore) {
+		return ErrNotYetValid
+	}
+	if v.conf.Issuer != "" && c.Issuer != v.conf.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.conf.Audience != "" && !contains(c.Audience, v.conf.Audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func numericDate(v *float64) time.Time {
+	if v == nil {
+		return time.Time{}
+	}
+
+	sec := int64(*v)
+	return time.Unix(sec, int64((*v-float64(sec))*float64(time.Second)))
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+func verifySignature(alg string, key interface{}, signed, sig []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch k := key.(type) {
+	case []byte:
+		if alg != HS256 {
+			return ErrUnknownKey
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+	case *rsa.PublicKey:
+		if alg != RS256 {
+			return ErrUnknownKey
+		}
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return ErrInvalidSignature
+		}
+	case *ecdsa.PublicKey:
+		if alg != ES256 || k.Curve.Params().BitSize != 256 {
+			return ErrUnknownKey
+		}
+		if len(sig) != 64 {
+			return ErrInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("unexpected key type %T", key)
+	}
+
+	return nil
+}
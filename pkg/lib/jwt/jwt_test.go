@@ -0,0 +1,160 @@
+// nolint:testpackage // overriding the verifier clock
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func sign(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	return signKid(t, alg, "", key, claims)
+}
+
+func signKid(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, _ := json.Marshal(header)
+	p, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	digest := sha256.Sum256([]byte(signed))
+
+	var (
+		sig []byte
+		err error
+	)
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, serr := ecdsa.Sign(rand.Reader, k, digest[:])
+		err = serr
+		if err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+		}
+	}
+	if err != nil {
+		t.Fatalf(`sign token: %v`, err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifier(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := &KeySet{}
+	ks.AddHMAC("", secret)
+	if err := ks.AddPublicKey("", &rsaKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.AddPublicKey("", &ecKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	v := NewVerifier(ks, Config{Issuer: "issuer", Audience: "apicompanies"})
+	v.now = func() time.Time { return now }
+
+	valid := map[string]interface{}{
+		"sub": "user",
+		"iss": "issuer",
+		"aud": []string{"other", "apicompanies"},
+		"exp": now.Add(time.Minute).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+	claim := func(k string, val interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for kk, vv := range valid {
+			c[kk] = vv
+		}
+		c[k] = val
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "hs256", token: sign(t, HS256, secret, valid)},
+		{name: "rs256", token: sign(t, RS256, rsaKey, valid)},
+		{name: "es256", token: sign(t, ES256, ecKey, valid)},
+		{name: "wrong secret", token: sign(t, HS256, []byte("other"), valid), wantErr: ErrInvalidSignature},
+		{name: "alg none", token: "eyJhbGciOiJub25lIn0.e30.", wantErr: ErrUnsupportedAlg},
+		{name: "expired", token: sign(t, HS256, secret, claim("exp", now.Unix())), wantErr: ErrExpired},
+		{name: "not yet valid", token: sign(t, HS256, secret, claim("nbf", now.Add(time.Minute).Unix())), wantErr: ErrNotYetValid},
+		{name: "issuer", token: sign(t, HS256, secret, claim("iss", "other")), wantErr: ErrInvalidIssuer},
+		{name: "audience", token: sign(t, HS256, secret, claim("aud", "other")), wantErr: ErrInvalidAudience},
+		{name: "malformed", token: "abc", wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf(`expected error %v, got %v`, tt.wantErr, err)
+			}
+			if err == nil && claims.Subject != "user" {
+				t.Fatalf(`expected subject %q, got %q`, "user", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestKeySetKid(t *testing.T) {
+	named, unnamed := []byte("named"), []byte("unnamed")
+
+	ks := &KeySet{}
+	ks.AddHMAC("a", named)
+	ks.AddHMAC("", unnamed)
+	v := NewVerifier(ks, Config{})
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "named key", token: signKid(t, HS256, "a", named, map[string]interface{}{})},
+		{name: "unnamed key for any kid", token: signKid(t, HS256, "b", unnamed, map[string]interface{}{})},
+		{name: "no kid", token: sign(t, HS256, named, map[string]interface{}{})},
+		{name: "key of another kid", token: signKid(t, HS256, "b", named, map[string]interface{}{}), wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(tt.token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf(`expected error %v, got %v`, tt.wantErr, err)
+			}
+		})
+	}
+}
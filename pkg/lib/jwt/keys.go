@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// KeySet holds verification keys. HMAC secrets verify HS256,
+// RSA keys verify RS256 and P-256 keys verify ES256 tokens.
+type KeySet struct {
+	keys []namedKey
+}
+
+type namedKey struct {
+	kid string
+	key interface{}
+}
+
+// AddHMAC adds a shared secret.
+func (ks *KeySet) AddHMAC(kid string, secret []byte) {
+	ks.keys = append(ks.keys, namedKey{kid: kid, key: secret})
+}
+
+// AddPublicKey adds an *rsa.PublicKey or an *ecdsa.PublicKey.
+func (ks *KeySet) AddPublicKey(kid string, key interface{}) error {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		ks.keys = append(ks.keys, namedKey{kid: kid, key: key})
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// Len returns the number of keys in the set.
+func (ks *KeySet) Len() int {
+	return len(ks.keys)
+}
+
+// LoadHMACFile adds a shared secret read from the file.
+// Surrounding whitespace is trimmed.
+func (ks *KeySet) LoadHMACFile(kid, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read secret: %w", err)
+	}
+
+	secret := bytes.TrimSpace(raw)
+	if len(secret) == 0 {
+		return errors.New("empty secret")
+	}
+	ks.AddHMAC(kid, secret)
+
+	return nil
+}
+
+// LoadPublicKeyFile adds a PEM encoded public key or certificate read from the file.
+func (ks *KeySet) LoadPublicKeyFile(kid, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return errors.New("no PEM data found")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("parse public key: %w", err)
+	}
+
+	return ks.AddPublicKey(kid, key)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+// LoadJWKSFile adds keys of a local JSON Web Key Set file.
+// RSA, P-256 EC and oct keys are supported, encryption keys are skipped.
+func (ks *KeySet) LoadJWKSFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read jwks: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		if err := ks.addJWK(k); err != nil {
+			return fmt.Errorf("jwks key %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (ks *KeySet) addJWK(k jwk) error {
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return fmt.Errorf("decode k: %w", err)
+		}
+		ks.AddHMAC(k.Kid, secret)
+		return nil
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return fmt.Errorf("decode n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return errors.New("invalid exponent")
+		}
+		return ks.AddPublicKey(k.Kid, &rsa.PublicKey{N: n, E: int(e.Int64())})
+	case "EC":
+		if k.Crv != "P-256" {
+			return fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return fmt.Errorf("decode x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return fmt.Errorf("decode y: %w", err)
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return errors.New("point is not on curve")
+		}
+		return ks.AddPublicKey(k.Kid, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y})
+	default:
+		return fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// verify checks the signature with the key named by kid, keys without
+// a name, e.g. read from a secret or PEM file, are tried for any kid.
+// Every key suitable for the algorithm is tried when kid is empty.
+func (ks *KeySet) verify(alg, kid string, signed, sig []byte) error {
+	switch alg {
+	case HS256, RS256, ES256:
+	default:
+		return ErrUnsupportedAlg
+	}
+
+	err := ErrUnknownKey
+	for _, k := range ks.keys {
+		if kid != "" && k.kid != "" && k.kid != kid {
+			continue
+		}
+
+		switch verr := verifySignature(alg, k.key, signed, sig); {
+		case verr == nil:
+			return nil
+		case errors.Is(verr, ErrUnknownKey):
+		default:
+			err = verr
+		}
+	}
+
+	return err
+}